@@ -0,0 +1,51 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+)
+
+// A report saved with OUTPUT_FORMAT=json, to compare the current one with
+func loadBaseline(path string) (*SpentTimeReport, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var baseline SpentTimeReport
+	if err := json.Unmarshal(content, &baseline); err != nil {
+		return nil, fmt.Errorf("could not parse %s: %w", path, err)
+	}
+
+	return &baseline, nil
+}
+
+// Hours of each user now and the difference with the baseline, users missing on one side count as 0h there
+func logBaselineDeltas(current SpentTimeReport, baseline SpentTimeReport, reportOptions ReportOptions) {
+	currentHours := make(map[string]float32)
+	baselineHours := make(map[string]float32)
+	var usernames []string
+	for _, user := range current.Users {
+		currentHours[user.Username] = user.Hours
+		usernames = append(usernames, user.Username)
+	}
+	for _, user := range baseline.Users {
+		baselineHours[user.Username] = user.Hours
+		if _, ok := currentHours[user.Username]; !ok {
+			usernames = append(usernames, user.Username)
+		}
+	}
+	sort.Strings(usernames)
+
+	log.Printf(sectionHeader(translate(reportOptions.Language, "-- Compared with %s to %s --")), baseline.StartDate, baseline.EndDate)
+	var currentTotal, baselineTotal float32
+	for _, username := range usernames {
+		log.Printf("%s : %.1fh (%+.1fh)", username, currentHours[username], currentHours[username]-baselineHours[username])
+		currentTotal += currentHours[username]
+		baselineTotal += baselineHours[username]
+	}
+	log.Printf("Total : %.1fh (%+.1fh)", currentTotal, currentTotal-baselineTotal)
+}
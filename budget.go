@@ -0,0 +1,41 @@
+package main
+
+import (
+	"log"
+	"regexp"
+	"time"
+)
+
+// Dev hours of everyone within the window, or on the fetched issues since they were created when cumulative
+func getBudgetDevHours(start time.Time, end time.Time, cumulative bool, trackingIssues []string, trackingPattern *regexp.Regexp, timelogData *TimelogData, reportOptions ReportOptions) float32 {
+	// a fixed bid budget covers the whole project, whoever logged the time
+	reportOptions.Usernames = nil
+	if cumulative {
+		start = time.Time{}
+	}
+
+	var devHours float32
+	for _, hours := range getAllUsersSpentTime(start, end, trackingIssues, trackingPattern, timelogData, reportOptions, newRunStats()).DevHours {
+		devHours += hours
+	}
+
+	return devHours
+}
+
+// Fails once the dev hours exceed the budget, only warns past warnThreshold percent of it
+func checkBudget(devHours float32, budgetHours float64, warnThreshold float64, reportOptions ReportOptions) error {
+	hours := roundHours(devHours, reportOptions.RoundingMode)
+	used := float64(devHours) / budgetHours * 100
+	log.Println(sectionHeader(translate(reportOptions.Language, "-- Budget --")))
+	if float64(devHours) > budgetHours {
+		log.Printf(colorize("Dev time : %.1fh / %gh (%.0f%%)", ansiRed), hours, budgetHours, used)
+		logger.Error("Dev time is OVER BUDGET", "devHours", hours, "budgetHours", budgetHours)
+		return exitErrorf(exitOverBudget, "%.1fh of dev time exceed the budget of %gh", hours, budgetHours)
+	}
+	log.Printf("Dev time : %.1fh / %gh (%.0f%%)", hours, budgetHours, used)
+	if warnThreshold > 0 && used >= warnThreshold {
+		logger.Warn("Dev time is close to the budget", "devHours", hours, "budgetHours", budgetHours, "warnThreshold", warnThreshold)
+	}
+
+	return nil
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"time"
+)
+
+// On-disk cache of the fetched issues, one JSON file per GraphQL endpoint and project path, a nil cache fetches every time
+type fileCache struct {
+	dir string
+	// the same project path on another Gitlab instance is another project
+	endpoint string
+	ttl      time.Duration
+	// ignore the cached files but still replace them with what is fetched
+	refresh bool
+}
+
+// Issue fields not part of the query are kept too, they are not in the Issue JSON
+type cachedIssue struct {
+	Issue
+	ProjectPath  string `json:"project_path"`
+	MergeRequest bool   `json:"merge_request"`
+}
+
+type cacheFile struct {
+	FetchedAt time.Time `json:"fetched_at"`
+	Endpoint  string    `json:"endpoint"`
+	// the cached issues are only reused for the same query, merge requests are appended to them when fetched
+	QueryOptions      QueryOptions  `json:"query_options"`
+	WithMergeRequests bool          `json:"with_merge_requests"`
+	Issues            []cachedIssue `json:"issues"`
+}
+
+// url escaping keeps namespaces apart, group/app and group_app must not share a file, query escaping leaves no colon in the endpoint directory
+func (c *fileCache) path(projectPath string) string {
+	return filepath.Join(c.dir, url.QueryEscape(c.endpoint), url.PathEscape(projectPath)+".json")
+}
+
+// Issues of a project cached less than ttl ago with the same query options, and with merge requests only if asked for
+func (c *fileCache) load(projectPath string, queryOptions QueryOptions, withMergeRequests bool) ([]Issue, time.Time, bool) {
+	if c.refresh {
+		return nil, time.Time{}, false
+	}
+
+	content, err := os.ReadFile(c.path(projectPath))
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			logger.Warn("Could not read cache file", "project", projectPath, "error", err)
+		}
+		return nil, time.Time{}, false
+	}
+	var cached cacheFile
+	if err := json.Unmarshal(content, &cached); err != nil {
+		logger.Warn("Ignoring malformed cache file", "project", projectPath, "error", err)
+		return nil, time.Time{}, false
+	}
+	if time.Since(cached.FetchedAt) > c.ttl || cached.Endpoint != c.endpoint || cached.WithMergeRequests != withMergeRequests || !sameQueryOptions(cached.QueryOptions, queryOptions) {
+		return nil, time.Time{}, false
+	}
+
+	issues := make([]Issue, 0, len(cached.Issues))
+	for _, cachedIssue := range cached.Issues {
+		issue := cachedIssue.Issue
+		issue.ProjectPath = cachedIssue.ProjectPath
+		issue.MergeRequest = cachedIssue.MergeRequest
+		issues = append(issues, issue)
+	}
+
+	return issues, cached.FetchedAt, true
+}
+
+// Written to a temporary file first so a concurrent run never reads half a file
+func (c *fileCache) store(projectPath string, queryOptions QueryOptions, withMergeRequests bool, issues []Issue) error {
+	cached := cacheFile{FetchedAt: time.Now(), Endpoint: c.endpoint, QueryOptions: queryOptions, WithMergeRequests: withMergeRequests, Issues: make([]cachedIssue, 0, len(issues))}
+	for _, issue := range issues {
+		cached.Issues = append(cached.Issues, cachedIssue{Issue: issue, ProjectPath: issue.ProjectPath, MergeRequest: issue.MergeRequest})
+	}
+	content, err := json.Marshal(cached)
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Dir(c.path(projectPath))
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+	file, err := os.CreateTemp(dir, "*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+	if _, err := file.Write(content); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(file.Name(), c.path(projectPath))
+}
+
+// A label list read back from JSON is nil when empty
+func sameQueryOptions(a QueryOptions, b QueryOptions) bool {
+	for _, options := range []*QueryOptions{&a, &b} {
+		if len(options.Labels) == 0 {
+			options.Labels = nil
+		}
+		if len(options.ExcludeLabels) == 0 {
+			options.ExcludeLabels = nil
+		}
+	}
+
+	return reflect.DeepEqual(a, b)
+}
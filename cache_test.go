@@ -0,0 +1,51 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFileCacheKey(t *testing.T) {
+	dir := t.TempDir()
+	queryOptions := QueryOptions{OperationName: "test", Labels: []string{"billable"}}
+	issues := []Issue{testIssueWith("1", "Feature", timelogAt("alice", "2024-03-04T09:00:00Z", 3600))}
+	stored := &fileCache{dir: dir, endpoint: "https://gitlab.example.com/api/graphql", ttl: time.Hour}
+	if err := stored.store("group/app", queryOptions, false, issues); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name              string
+		endpoint          string
+		projectPath       string
+		queryOptions      QueryOptions
+		withMergeRequests bool
+		wantHit           bool
+	}{
+		{"same project, endpoint and options", stored.endpoint, "group/app", queryOptions, false, true},
+		{"with merge requests", stored.endpoint, "group/app", queryOptions, true, false},
+		{"same project path on another instance", "https://gitlab.com/api/graphql", "group/app", queryOptions, false, false},
+		{"other query options", stored.endpoint, "group/app", QueryOptions{OperationName: "test"}, false, false},
+		{"other project", stored.endpoint, "group/api", queryOptions, false, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cache := &fileCache{dir: dir, endpoint: test.endpoint, ttl: time.Hour}
+			cached, _, ok := cache.load(test.projectPath, test.queryOptions, test.withMergeRequests)
+			if ok != test.wantHit {
+				t.Fatalf("cache hit = %v, want %v", ok, test.wantHit)
+			}
+			if ok && (len(cached) != 1 || cached[0].ProjectPath != "group/app") {
+				t.Errorf("cached issues = %+v, want the stored issue", cached)
+			}
+		})
+	}
+
+	// merge requests cached for the same project are a separate query, they replace the entry rather than mix with it
+	if err := stored.store("group/app", queryOptions, true, issues); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, ok := stored.load("group/app", queryOptions, false); ok {
+		t.Error("issues cached with merge requests are served to a run without them")
+	}
+}
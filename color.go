@@ -0,0 +1,43 @@
+package main
+
+import (
+	"os"
+)
+
+const (
+	ansiBold  = "\x1b[1m"
+	ansiRed   = "\x1b[31m"
+	ansiCyan  = "\x1b[36m"
+	ansiReset = "\x1b[0m"
+)
+
+// Whether the text report is colored, set once by run like the logger, json and csv outputs never are
+var colorOutput = false
+
+// auto colors a terminal unless NO_COLOR is set, always and never override both
+func useColor(mode string, out *os.File) bool {
+	switch mode {
+	case "always":
+		return true
+	case "never":
+		return false
+	}
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	info, err := out.Stat()
+
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+func colorize(s string, code string) string {
+	if !colorOutput || code == "" {
+		return s
+	}
+	return code + s + ansiReset
+}
+
+// Section headers such as -- Capacity --, a format string stays one since the codes have no %
+func sectionHeader(s string) string {
+	return colorize(s, ansiBold)
+}
@@ -0,0 +1,281 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Options that contradict each other, checked once the env files are loaded
+var incompatibleOptions = []struct {
+	options string
+	reason  string
+	applies func(getenv func(string) string) bool
+	// the option is only used by some formats, with several OUTPUT_FORMAT it conflicts when none of them uses it
+	everyFormat bool
+}{
+	{
+		options: "SCOPE + GITLAB_PROJECT_PATH",
+		reason:  "SCOPE=my-projects discovers the projects, GITLAB_PROJECT_PATH would be ignored",
+		applies: func(getenv func(string) string) bool {
+			return getenv("SCOPE") != "" && getenv("GITLAB_PROJECT_PATH") != ""
+		},
+	},
+	{
+		options: "SCOPE + GITLAB_GROUP_PATH",
+		reason:  "SCOPE=my-projects discovers the projects, GITLAB_GROUP_PATH would be ignored",
+		applies: func(getenv func(string) string) bool {
+			return getenv("SCOPE") != "" && getenv("GITLAB_GROUP_PATH") != ""
+		},
+	},
+	{
+		options: "GITLAB_GROUP_PATH + WITH_MERGE_REQUESTS",
+		reason:  "merge requests are only fetched per project",
+		applies: func(getenv func(string) string) bool {
+			return getenv("GITLAB_GROUP_PATH") != "" && getenv("WITH_MERGE_REQUESTS") == "true"
+		},
+	},
+	{
+		options: "SERVE_ADDR + START_DATE|END_DATE",
+		reason:  "served reports end today and look back the days of the request",
+		applies: func(getenv func(string) string) bool {
+			return getenv("SERVE_ADDR") != "" && (getenv("START_DATE") != "" || getenv("END_DATE") != "")
+		},
+	},
+	{
+		options: "SERVE_ADDR + SLACK_WEBHOOK_URL",
+		reason:  "the summary is posted after a single report, served reports are never posted",
+		applies: func(getenv func(string) string) bool {
+			return getenv("SERVE_ADDR") != "" && getenv("SLACK_WEBHOOK_URL") != ""
+		},
+	},
+	{
+		options: "SERVE_ADDR + WEBHOOK_URL",
+		reason:  "the report is posted after a single report, served reports are never posted",
+		applies: func(getenv func(string) string) bool {
+			return getenv("SERVE_ADDR") != "" && getenv("WEBHOOK_URL") != ""
+		},
+	},
+	{
+		options: "SERVE_ADDR + CHUNK_BY",
+		reason:  "the chunks are the months of a single report window, served reports each have their own",
+		applies: func(getenv func(string) string) bool {
+			return getenv("SERVE_ADDR") != "" && getenv("CHUNK_BY") != ""
+		},
+	},
+	{
+		options: "SERVE_ADDR + BUDGET_HOURS",
+		reason:  "the budget is checked once after a single report, a server never exits",
+		applies: func(getenv func(string) string) bool {
+			return getenv("SERVE_ADDR") != "" && getenv("BUDGET_HOURS") != ""
+		},
+	},
+	{
+		options: "SERVE_ADDR + DRY_RUN",
+		reason:  "a dry run stops before the first query, there would be nothing to serve",
+		applies: func(getenv func(string) string) bool {
+			return getenv("SERVE_ADDR") != "" && getenv("DRY_RUN") == "true"
+		},
+	},
+	{
+		options: "SERVE_ADDR + OUTPUT_FILE",
+		reason:  "served reports are written in the HTTP responses",
+		applies: func(getenv func(string) string) bool {
+			return getenv("SERVE_ADDR") != "" && getenv("OUTPUT_FILE") != ""
+		},
+	},
+	{
+		options: "SERVE_ADDR + OUTPUT_FORMAT",
+		reason:  "served reports are always JSON",
+		applies: func(getenv func(string) string) bool {
+			return getenv("SERVE_ADDR") != "" && getenv("OUTPUT_FORMAT") != "" && getenv("OUTPUT_FORMAT") != "json"
+		},
+	},
+	{
+		options: "TIMEZONE + DAY_BOUNDARY=utc",
+		reason:  "both set the timezone of the day boundaries",
+		applies: func(getenv func(string) string) bool {
+			return getenv("TIMEZONE") != "" && getenv("DAY_BOUNDARY") == "utc"
+		},
+	},
+	{
+		options: "START_DATE|END_DATE + PERIOD",
+		reason:  "PERIOD computes its own window ending today",
+		applies: func(getenv func(string) string) bool {
+			return (getenv("START_DATE") != "" || getenv("END_DATE") != "") && getenv("PERIOD") != ""
+		},
+	},
+	{
+		options: "START_DATE|END_DATE + SINCE_TIMESTAMP",
+		reason:  "SINCE_TIMESTAMP reports timelogs recorded after a moment, whatever the window",
+		applies: func(getenv func(string) string) bool {
+			return (getenv("START_DATE") != "" || getenv("END_DATE") != "") && getenv("SINCE_TIMESTAMP") != ""
+		},
+	},
+	{
+		options: "SINCE_TIMESTAMP + PERIOD",
+		reason:  "SINCE_TIMESTAMP reports timelogs recorded after a moment, whatever the window",
+		applies: func(getenv func(string) string) bool {
+			return getenv("SINCE_TIMESTAMP") != "" && getenv("PERIOD") != ""
+		},
+	},
+	{
+		options: "SINCE_TIMESTAMP + GROUP_BY",
+		reason:  "SINCE_TIMESTAMP prints its own list of timelogs and is never grouped",
+		applies: func(getenv func(string) string) bool {
+			return getenv("SINCE_TIMESTAMP") != "" && getenv("GROUP_BY") != ""
+		},
+	},
+	{
+		options: "SINCE_TIMESTAMP + OUTPUT_FORMAT",
+		reason:  "SINCE_TIMESTAMP only has a text output",
+		applies: func(getenv func(string) string) bool {
+			return getenv("SINCE_TIMESTAMP") != "" && getenv("OUTPUT_FORMAT") != "" && getenv("OUTPUT_FORMAT") != "text"
+		},
+	},
+	{
+		options: "GROUP_BY + OUTPUT_FORMAT=csv|harvest|toggl",
+		reason:  "csv and timesheet exports list every timelog and are never grouped",
+		applies: func(getenv func(string) string) bool {
+			_, isTimesheet := timesheetFormats[getenv("OUTPUT_FORMAT")]
+			return getenv("GROUP_BY") != "" && (isTimesheet || getenv("OUTPUT_FORMAT") == "csv")
+		},
+	},
+	{
+		options: "COLLAPSE_ENTRIES + OUTPUT_FORMAT=csv|harvest|toggl",
+		reason:  "csv and timesheet exports always have one row per timelog",
+		applies: func(getenv func(string) string) bool {
+			_, isTimesheet := timesheetFormats[getenv("OUTPUT_FORMAT")]
+			return getenv("COLLAPSE_ENTRIES") == "true" && (isTimesheet || getenv("OUTPUT_FORMAT") == "csv")
+		},
+	},
+	{
+		options: "CUMULATIVE + OUTPUT_FORMAT",
+		reason:  "the running total is printed on the detail lines of the text report",
+		applies: func(getenv func(string) string) bool {
+			return getenv("CUMULATIVE") == "true" && getenv("OUTPUT_FORMAT") != "" && getenv("OUTPUT_FORMAT") != "text"
+		},
+		everyFormat: true,
+	},
+	{
+		options: "CUMULATIVE + GROUP_BY",
+		reason:  "grouped reports print no detail lines",
+		applies: func(getenv func(string) string) bool {
+			return getenv("CUMULATIVE") == "true" && getenv("GROUP_BY") != ""
+		},
+	},
+	{
+		options: "OUTPUT_FORMAT=markdown + GROUP_BY",
+		reason:  "the markdown report always has a per user table and a per issue breakdown",
+		applies: func(getenv func(string) string) bool {
+			return getenv("OUTPUT_FORMAT") == "markdown" && getenv("GROUP_BY") != ""
+		},
+	},
+	{
+		options: "OUTPUT_FORMAT=json + GROUP_BY=epic|milestone|iteration",
+		reason:  "only the user, all users, GROUP_BY=issue and GROUP_BY=date reports have a JSON output",
+		applies: func(getenv func(string) string) bool {
+			return getenv("OUTPUT_FORMAT") == "json" && getenv("GROUP_BY") != "" && getenv("GROUP_BY") != "date" && getenv("GROUP_BY") != "issue"
+		},
+	},
+	{
+		options: "GITLAB_ITERATION + GROUP_BY=iteration",
+		reason:  "a single iteration is all the iteration report would show",
+		applies: func(getenv func(string) string) bool {
+			return getenv("GITLAB_ITERATION") != "" && getenv("GROUP_BY") == "iteration"
+		},
+	},
+	{
+		options: "BREAKDOWN=daily + ALL_USERS",
+		reason:  "BREAKDOWN=daily only applies to the single user report",
+		applies: func(getenv func(string) string) bool {
+			return getenv("BREAKDOWN") == "daily" && getenv("ALL_USERS") != ""
+		},
+	},
+	{
+		options: "BREAKDOWN + GROUP_BY",
+		reason:  "BREAKDOWN only applies to the user and all users reports",
+		applies: func(getenv func(string) string) bool {
+			return getenv("BREAKDOWN") != "" && getenv("GROUP_BY") != ""
+		},
+	},
+	{
+		options: "BASELINE_FILE + GROUP_BY|SINCE_TIMESTAMP|OUTPUT_FORMAT=csv|markdown|harvest|toggl",
+		reason:  "only the user and all users reports are compared with the baseline",
+		applies: func(getenv func(string) string) bool {
+			outputFormat := getenv("OUTPUT_FORMAT")
+			return getenv("BASELINE_FILE") != "" && (getenv("GROUP_BY") != "" || getenv("SINCE_TIMESTAMP") != "" || (outputFormat != "" && outputFormat != "text" && outputFormat != "json"))
+		},
+		everyFormat: true,
+	},
+	{
+		options: "EXCEL_BOM|CSV_BOM|CSV_DELIMITER|CSV_DECIMAL_SEPARATOR + OUTPUT_FORMAT other than csv",
+		reason:  "only the csv report is formatted for spreadsheets, harvest and toggl imports keep their own format",
+		applies: func(getenv func(string) string) bool {
+			csvFormatting := getenv("EXCEL_BOM") == "true" || getenv("CSV_BOM") == "true" || getenv("CSV_DELIMITER") != "" || getenv("CSV_DECIMAL_SEPARATOR") != ""
+			return csvFormatting && getenv("OUTPUT_FORMAT") != "csv"
+		},
+		everyFormat: true,
+	},
+	{
+		options: "CSV_DECIMAL_SEPARATOR=, + CSV_DELIMITER=,",
+		reason:  "every hours cell would be quoted, use CSV_DELIMITER=; with a decimal comma",
+		applies: func(getenv func(string) string) bool {
+			return getenv("CSV_DECIMAL_SEPARATOR") == "," && (getenv("CSV_DELIMITER") == "" || getenv("CSV_DELIMITER") == ",")
+		},
+	},
+	{
+		options: "WITH_MERGE_REQUESTS + SEARCH",
+		reason:  "SEARCH only applies to issues, merge requests would all be reported",
+		applies: func(getenv func(string) string) bool {
+			return getenv("WITH_MERGE_REQUESTS") == "true" && getenv("SEARCH") != ""
+		},
+	},
+	{
+		options: "WITH_MERGE_REQUESTS + several GITLAB_MILESTONE",
+		reason:  "merge requests are filtered on a single milestone title",
+		applies: func(getenv func(string) string) bool {
+			return getenv("WITH_MERGE_REQUESTS") == "true" && len(splitList(getenv("GITLAB_MILESTONE"))) > 1
+		},
+	},
+	{
+		options: "WITH_MERGE_REQUESTS + GITLAB_ITERATION|GROUP_BY=iteration",
+		reason:  "merge requests have no iteration",
+		applies: func(getenv func(string) string) bool {
+			return getenv("WITH_MERGE_REQUESTS") == "true" && (getenv("GITLAB_ITERATION") != "" || getenv("GROUP_BY") == "iteration")
+		},
+	},
+}
+
+// Reject contradictory options with an explanation rather than producing a confusing report
+// Each format of a comma-separated OUTPUT_FORMAT is checked as if it were the only one, an option used by some formats only needs one of them
+func validateConfig(getenv func(string) string) error {
+	outputFormats := splitList(getenv("OUTPUT_FORMAT"))
+	if len(outputFormats) == 0 {
+		outputFormats = []string{""}
+	}
+
+	var conflicts []string
+	for _, incompatible := range incompatibleOptions {
+		formatsApplying := 0
+		for _, outputFormat := range outputFormats {
+			formatGetenv := func(key string) string {
+				if key == "OUTPUT_FORMAT" {
+					return outputFormat
+				}
+				return getenv(key)
+			}
+			if incompatible.applies(formatGetenv) {
+				formatsApplying++
+			}
+		}
+		if formatsApplying > 0 && (!incompatible.everyFormat || formatsApplying == len(outputFormats)) {
+			conflicts = append(conflicts, fmt.Sprintf("%s: %s", incompatible.options, incompatible.reason))
+		}
+	}
+
+	if len(conflicts) > 0 {
+		return fmt.Errorf("incompatible options\n  %s", strings.Join(conflicts, "\n  "))
+	}
+
+	return nil
+}
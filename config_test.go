@@ -0,0 +1,40 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateConfigOutputFormats(t *testing.T) {
+	tests := []struct {
+		name         string
+		env          map[string]string
+		wantConflict []string
+	}{
+		{"single format", map[string]string{"OUTPUT_FORMAT": "csv", "GROUP_BY": "epic"}, []string{"GROUP_BY + OUTPUT_FORMAT=csv|harvest|toggl"}},
+		{"each listed format is checked", map[string]string{"OUTPUT_FORMAT": "text, csv", "COLLAPSE_ENTRIES": "true"}, []string{"COLLAPSE_ENTRIES + OUTPUT_FORMAT=csv|harvest|toggl"}},
+		{"a conflict of several formats is listed once", map[string]string{"OUTPUT_FORMAT": "csv,harvest", "GROUP_BY": "issue"}, []string{"GROUP_BY + OUTPUT_FORMAT=csv|harvest|toggl"}},
+		{"formats without conflicts", map[string]string{"OUTPUT_FORMAT": "text,json,markdown"}, nil},
+		{"csv options with csv listed, text options without text", map[string]string{"OUTPUT_FORMAT": "json,csv", "CSV_DELIMITER": ";", "CUMULATIVE": "true"}, []string{"CUMULATIVE + OUTPUT_FORMAT"}},
+		{"csv options without csv listed", map[string]string{"OUTPUT_FORMAT": "json,text", "EXCEL_BOM": "true"}, []string{"EXCEL_BOM|CSV_BOM|CSV_DELIMITER|CSV_DECIMAL_SEPARATOR + OUTPUT_FORMAT other than csv"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := validateConfig(func(key string) string { return test.env[key] })
+			if len(test.wantConflict) == 0 {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("no error, want %v", test.wantConflict)
+			}
+			for _, conflict := range test.wantConflict {
+				if count := strings.Count(err.Error(), conflict); count != 1 {
+					t.Errorf("%q listed %d times in %v", conflict, count, err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,295 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// Options of a run keyed by their env var names, read once by run from the env, flags and env files included, and from CONFIG_FILE
+// Values are kept as written, runWithConfig parses and checks them
+type Config struct {
+	AgingReport            string `env:"AGING_REPORT"`
+	AgingThreshold         string `env:"AGING_THRESHOLD"`
+	AllUsers               string `env:"ALL_USERS"`
+	BaselineFile           string `env:"BASELINE_FILE"`
+	Breakdown              string `env:"BREAKDOWN"`
+	BudgetCumulative       string `env:"BUDGET_CUMULATIVE"`
+	BudgetHours            string `env:"BUDGET_HOURS"`
+	CacheDir               string `env:"CACHE_DIR"`
+	CacheRefresh           string `env:"CACHE_REFRESH"`
+	CacheTTL               string `env:"CACHE_TTL"`
+	CapacitiesFile         string `env:"CAPACITIES_FILE"`
+	CACertFile             string `env:"CA_CERT_FILE"`
+	ChunkBy                string `env:"CHUNK_BY"`
+	ClockSkewThreshold     string `env:"CLOCK_SKEW_THRESHOLD"`
+	CollapseEntries        string `env:"COLLAPSE_ENTRIES"`
+	Color                  string `env:"COLOR"`
+	Concurrency            string `env:"CONCURRENCY"`
+	CostReport             string `env:"COST_REPORT"`
+	CSVBOM                 string `env:"CSV_BOM"`
+	CSVDecimalSeparator    string `env:"CSV_DECIMAL_SEPARATOR"`
+	CSVDelimiter           string `env:"CSV_DELIMITER"`
+	Cumulative             string `env:"CUMULATIVE"`
+	CumulativeBy           string `env:"CUMULATIVE_BY"`
+	Currency               string `env:"CURRENCY"`
+	DaysNum                string `env:"DAYS_NUM"`
+	DayBoundary            string `env:"DAY_BOUNDARY"`
+	DefaultCapacity        string `env:"DEFAULT_CAPACITY"`
+	DryRun                 string `env:"DRY_RUN"`
+	EndDate                string `env:"END_DATE"`
+	EstimateReport         string `env:"ESTIMATE_REPORT"`
+	ExcelBOM               string `env:"EXCEL_BOM"`
+	ExcludeLabels          string `env:"EXCLUDE_LABELS"`
+	ExcludeUsers           string `env:"EXCLUDE_USERS"`
+	FollowMovedIssues      string `env:"FOLLOW_MOVED_ISSUES"`
+	GitlabAssignee         string `env:"GITLAB_ASSIGNEE"`
+	GitlabGraphQLURL       string `env:"GITLAB_GRAPHQL_URL"`
+	GitlabGroupPath        string `env:"GITLAB_GROUP_PATH"`
+	GitlabHost             string `env:"GITLAB_HOST"`
+	GitlabIteration        string `env:"GITLAB_ITERATION"`
+	GitlabMilestone        string `env:"GITLAB_MILESTONE"`
+	GitlabProjectPath      string `env:"GITLAB_PROJECT_PATH"`
+	GitlabReportingIssue   string `env:"GITLAB_REPORTING_ISSUE"`
+	GitlabReportingPattern string `env:"GITLAB_REPORTING_PATTERN"`
+	GitlabToken            string `env:"GITLAB_TOKEN"`
+	GroupBy                string `env:"GROUP_BY"`
+	IncludeGhostUser       string `env:"INCLUDE_GHOST_USER"`
+	InsecureSkipVerify     string `env:"INSECURE_SKIP_VERIFY"`
+	IssueDetailMinHours    string `env:"ISSUE_DETAIL_MIN_HOURS"`
+	IssueState             string `env:"ISSUE_STATE"`
+	JobName                string `env:"JOB_NAME"`
+	KeyBy                  string `env:"KEY_BY"`
+	Labels                 string `env:"LABELS"`
+	Lang                   string `env:"LANG"`
+	LogFormat              string `env:"LOG_FORMAT"`
+	LogLevel               string `env:"LOG_LEVEL"`
+	MaxRetries             string `env:"MAX_RETRIES"`
+	MembersSource          string `env:"MEMBERS_SOURCE"`
+	MixedIssuesFile        string `env:"MIXED_ISSUES_FILE"`
+	NoCache                string `env:"NO_CACHE"`
+	OAuthClientID          string `env:"OAUTH_CLIENT_ID"`
+	OAuthClientSecret      string `env:"OAUTH_CLIENT_SECRET"`
+	OAuthRefreshToken      string `env:"OAUTH_REFRESH_TOKEN"`
+	OAuthRefreshTokenFile  string `env:"OAUTH_REFRESH_TOKEN_FILE"`
+	OperationName          string `env:"OPERATION_NAME"`
+	OutputFile             string `env:"OUTPUT_FILE"`
+	OutputFormat           string `env:"OUTPUT_FORMAT"`
+	OutputTimezone         string `env:"OUTPUT_TIMEZONE"`
+	Period                 string `env:"PERIOD"`
+	PrintQuery             string `env:"PRINT_QUERY"`
+	PushgatewayAddr        string `env:"PUSHGATEWAY_ADDR"` // older name of PUSHGATEWAY_URL
+	PushgatewayURL         string `env:"PUSHGATEWAY_URL"`
+	RatesFile              string `env:"RATES_FILE"`
+	RequestTimeout         string `env:"REQUEST_TIMEOUT"`
+	RequireAllUsersLogged  string `env:"REQUIRE_ALL_USERS_LOGGED"`
+	RetryOnEmpty           string `env:"RETRY_ON_EMPTY"`
+	RoundingMode           string `env:"ROUNDING_MODE"`
+	RunTag                 string `env:"RUN_TAG"`
+	Scope                  string `env:"SCOPE"`
+	Search                 string `env:"SEARCH"`
+	ServeAddr              string `env:"SERVE_ADDR"`
+	ServeCacheTTL          string `env:"SERVE_CACHE_TTL"`
+	ShowEmptyDays          string `env:"SHOW_EMPTY_DAYS"`
+	ShowStats              string `env:"SHOW_STATS"`
+	ShowUpdatedAt          string `env:"SHOW_UPDATED_AT"`
+	ShowZero               string `env:"SHOW_ZERO"`
+	SinceTimestamp         string `env:"SINCE_TIMESTAMP"`
+	SlackWebhookURL        string `env:"SLACK_WEBHOOK_URL"`
+	SortBy                 string `env:"SORT_BY"`
+	Sparkline              string `env:"SPARKLINE"`
+	StartDate              string `env:"START_DATE"`
+	Stats                  string `env:"STATS"`
+	Tee                    string `env:"TEE"`
+	TimesheetClient        string `env:"TIMESHEET_CLIENT"`
+	TimeOffLabel           string `env:"TIMEOFF_LABEL"`
+	Timezone               string `env:"TIMEZONE"`
+	TopN                   string `env:"TOP_N"`
+	Usernames              string `env:"USERNAMES"`
+	UserAgent              string `env:"USER_AGENT"`
+	Velocity               string `env:"VELOCITY"`
+	VerifyNotes            string `env:"VERIFY_NOTES"`
+	VerifyTotals           string `env:"VERIFY_TOTALS"`
+	WarnThreshold          string `env:"WARN_THRESHOLD"`
+	WebhookAuthHeader      string `env:"WEBHOOK_AUTH_HEADER"`
+	WebhookURL             string `env:"WEBHOOK_URL"`
+	WithMergeRequests      string `env:"WITH_MERGE_REQUESTS"`
+}
+
+// Keys of the config file, the env vars it can set, ENV_FILES is left out since env files are loaded before it
+var configKeys = func() []string {
+	configType := reflect.TypeOf(Config{})
+	keys := make([]string, configType.NumField())
+	for i := range keys {
+		keys[i] = configType.Field(i).Tag.Get("env")
+	}
+
+	return keys
+}()
+
+// Value of an option by its env var name, empty when it is unset or unknown
+func (c Config) get(key string) string {
+	for i, configKey := range configKeys {
+		if configKey == key {
+			return reflect.ValueOf(c).Field(i).String()
+		}
+	}
+
+	return ""
+}
+
+// Options of a run, env vars set win over the values of the config file at path, read when path is not empty
+func loadConfig(lookupEnv func(string) (string, bool), path string) (Config, error) {
+	var fileValues map[string]string
+	if path != "" {
+		var err error
+		if fileValues, err = readConfigFile(path); err != nil {
+			return Config{}, err
+		}
+	}
+
+	var config Config
+	fields := reflect.ValueOf(&config).Elem()
+	for i, key := range configKeys {
+		value, ok := lookupEnv(key)
+		if !ok {
+			value = fileValues[key]
+		}
+		fields.Field(i).SetString(value)
+	}
+
+	return config, nil
+}
+
+// YAML or TOML config file keyed by env var names, TOML when its extension is .toml
+// Lists are joined with commas, so GITLAB_PROJECT_PATH can be written one project per line
+func readConfigFile(path string) (map[string]string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	parse := parseYAMLConfig
+	if strings.EqualFold(filepath.Ext(path), ".toml") {
+		parse = parseTOMLConfig
+	}
+	values, err := parse(string(content))
+	if err != nil {
+		return nil, fmt.Errorf("could not parse %s: %w", path, err)
+	}
+
+	var unknownKeys []string
+	for key := range values {
+		if !slices.Contains(configKeys, key) {
+			unknownKeys = append(unknownKeys, key)
+		}
+	}
+	if len(unknownKeys) > 0 {
+		sort.Strings(unknownKeys)
+		return nil, fmt.Errorf("unknown keys in %s: %s", path, strings.Join(unknownKeys, ", "))
+	}
+
+	return values, nil
+}
+
+func parseYAMLConfig(content string) (map[string]string, error) {
+	// nodes keep scalars as written, 2024-01-31 or 0800 are not turned into a date or a number
+	var nodes map[string]yaml.Node
+	if err := yaml.Unmarshal([]byte(content), &nodes); err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]string, len(nodes))
+	for key, node := range nodes {
+		value, err := configValue(node)
+		if err != nil {
+			return nil, fmt.Errorf("%s %w", key, err)
+		}
+		values[key] = value
+	}
+
+	return values, nil
+}
+
+func configValue(node yaml.Node) (string, error) {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		return node.Value, nil
+	case yaml.SequenceNode:
+		values := make([]string, 0, len(node.Content))
+		for _, item := range node.Content {
+			if item.Kind != yaml.ScalarNode {
+				return "", fmt.Errorf("must be a list of values")
+			}
+			values = append(values, item.Value)
+		}
+		return strings.Join(values, ","), nil
+	}
+
+	return "", fmt.Errorf("must be a value or a list of values")
+}
+
+// Only the top level keys of TOML, the env vars have no tables, values are formatted back as they are written in an env file
+func parseTOMLConfig(content string) (map[string]string, error) {
+	var document map[string]any
+	if _, err := toml.Decode(content, &document); err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]string, len(document))
+	for key, value := range document {
+		if _, ok := value.(map[string]any); ok {
+			return nil, fmt.Errorf("%s is a table, tables are not supported, keys are env var names", key)
+		}
+		items, ok := value.([]any)
+		if !ok {
+			items = []any{value}
+		}
+		formatted := make([]string, 0, len(items))
+		for _, item := range items {
+			itemValue, err := tomlScalar(item)
+			if err != nil {
+				return nil, fmt.Errorf("%s %w", key, err)
+			}
+			formatted = append(formatted, itemValue)
+		}
+		values[key] = strings.Join(formatted, ",")
+	}
+
+	return values, nil
+}
+
+func tomlScalar(value any) (string, error) {
+	switch value := value.(type) {
+	case string:
+		return value, nil
+	case bool:
+		return strconv.FormatBool(value), nil
+	case int64:
+		return strconv.FormatInt(value, 10), nil
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64), nil
+	case time.Time:
+		// local dates and times are decoded with a location named after their TOML type
+		switch value.Location().String() {
+		case "date-local":
+			return value.Format("2006-01-02"), nil
+		case "time-local":
+			return value.Format("15:04:05"), nil
+		case "datetime-local":
+			return value.Format("2006-01-02T15:04:05"), nil
+		}
+		return value.Format(time.RFC3339), nil
+	}
+
+	return "", fmt.Errorf("must be a value or a list of values")
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func writeConfigFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestReadConfigFile(t *testing.T) {
+	want := map[string]string{
+		"GITLAB_HOST":            "https://gitlab.example.com",
+		"GITLAB_PROJECT_PATH":    "group/app,group/api",
+		"START_DATE":             "2024-01-31",
+		"TOP_N":                  "05",
+		"DRY_RUN":                "true",
+		"GITLAB_REPORTING_ISSUE": "Meetings # weekly,Support",
+	}
+
+	tests := []struct {
+		name    string
+		file    string
+		content string
+	}{
+		{"yaml", "config.yaml", `
+GITLAB_HOST: https://gitlab.example.com
+GITLAB_PROJECT_PATH:
+  - group/app
+  - group/api
+START_DATE: 2024-01-31
+TOP_N: 05
+DRY_RUN: true
+GITLAB_REPORTING_ISSUE: ["Meetings # weekly", Support]
+`},
+		{"toml", "config.toml", `
+# keys are the env var names
+GITLAB_HOST = "https://gitlab.example.com"
+GITLAB_PROJECT_PATH = [
+  "group/app", # the main project
+  'group/api',
+]
+START_DATE = 2024-01-31
+TOP_N = "05" # a bare 05 is not a valid TOML integer
+DRY_RUN = true # skips the queries
+GITLAB_REPORTING_ISSUE = ["Meetings # weekly", "Support"]
+`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			values, err := readConfigFile(writeConfigFile(t, test.file, test.content))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(values, want) {
+				t.Errorf("values = %v, want %v", values, want)
+			}
+		})
+	}
+}
+
+func TestReadConfigFileErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		wantErr string
+	}{
+		{"unknown yaml key", "config.yml", "GITLAB_HOST: gitlab.example.com\nGITLAB_HOTS: typo\n", "unknown keys in"},
+		{"unknown toml key", "config.toml", "GITLAB_HOTS = \"typo\"\n", "unknown keys in"},
+		{"yaml mapping", "config.yaml", "GITLAB_PROJECT_PATH:\n  app: group/app\n", "GITLAB_PROJECT_PATH must be a value or a list of values"},
+		{"toml table", "config.toml", "GITLAB_HOST = \"gitlab.example.com\"\n\n[gitlab]\nhost = \"gitlab.com\"\n", "gitlab is a table, tables are not supported"},
+		{"toml nested array", "config.toml", "USERNAMES = [[\"alice\"]]\n", "USERNAMES must be a value or a list of values"},
+		{"toml key set twice", "config.toml", "TOP_N = 5\nTOP_N = 10\n", "line 2 (last key \"TOP_N\"): Key 'TOP_N' has already been defined"},
+		{"toml unterminated string", "config.toml", "GITLAB_HOST = \"gitlab.example.com\nTOP_N = 5\n", "line 1 (last key \"GITLAB_HOST\"): strings cannot contain newlines"},
+		{"toml unterminated array", "config.toml", "USERNAMES = [\"alice\",\n", "unexpected EOF; expected value"},
+		{"toml trailing text", "config.toml", "TOP_N = 5 10\n", "line 1: expected a top-level item to end with a newline, comment, or EOF"},
+		{"toml line without value", "config.toml", "DRY_RUN\n", "line 1: expected '.' or '='"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := readConfigFile(writeConfigFile(t, test.file, test.content))
+			if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+				t.Errorf("error = %v, want it to contain %q", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	path := writeConfigFile(t, "config.toml", "GITLAB_HOST = \"https://gitlab.example.com\"\nTIMEZONE = \"Europe/Paris\"\nTOP_N = 5\n")
+	env := map[string]string{
+		"TIMEZONE": "UTC",
+		// set but empty still wins, as it does for an env var over the env files
+		"TOP_N":  "",
+		"LABELS": "billable",
+	}
+	lookupEnv := func(key string) (string, bool) {
+		value, ok := env[key]
+		return value, ok
+	}
+
+	config, err := loadConfig(lookupEnv, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Config{GitlabHost: "https://gitlab.example.com", Timezone: "UTC", Labels: "billable"}
+	if config != want {
+		t.Errorf("config = %+v, want %+v", config, want)
+	}
+	if got := config.get("GITLAB_HOST"); got != want.GitlabHost {
+		t.Errorf("get(GITLAB_HOST) = %q, want %q", got, want.GitlabHost)
+	}
+
+	if _, err := loadConfig(lookupEnv, filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Error("a missing config file is not reported")
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	gitlab "github.com/xanzy/go-gitlab"
+	"golang.org/x/oauth2"
+)
+
+// Exit codes, so pipelines can tell a bad configuration from an unreachable Gitlab
+const (
+	exitFailure = 1
+	exitConfig  = 2
+	exitAuth    = 3
+	exitNetwork = 4
+	// users expected to log time did not
+	exitNoData = 5
+	// the project or group path does not exist or the token cannot see it
+	exitNotFound = 6
+	// more dev time was logged than BUDGET_HOURS
+	exitOverBudget = 7
+)
+
+// Gitlab answers a null project rather than an error to a misspelled path or a token without access
+var errProjectNotFound = errors.New("project or group not found, or the token has no access to it")
+
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string {
+	return e.err.Error()
+}
+
+func (e *exitError) Unwrap() error {
+	return e.err
+}
+
+func exitErrorf(code int, format string, args ...interface{}) error {
+	return &exitError{code: code, err: fmt.Errorf(format, args...)}
+}
+
+func exitCode(err error) int {
+	var exitErr *exitError
+	if errors.As(err, &exitErr) {
+		return exitErr.code
+	}
+
+	return exitFailure
+}
+
+// Gitlab answers 401 or 403 to a bad or under-scoped token, anything else is taken as a network failure
+func gitlabExitCode(err error) int {
+	// a revoked or expired OAuth2 refresh token
+	var retrieveError *oauth2.RetrieveError
+	if errors.As(err, &retrieveError) {
+		return exitAuth
+	}
+	var errorResponse *gitlab.ErrorResponse
+	if errors.As(err, &errorResponse) && errorResponse.Response != nil {
+		if status := errorResponse.Response.StatusCode; status == http.StatusUnauthorized || status == http.StatusForbidden {
+			return exitAuth
+		}
+	}
+
+	return exitNetwork
+}
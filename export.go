@@ -0,0 +1,293 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Timesheet formats accepted by OUTPUT_FORMAT besides the default text report
+var timesheetFormats = map[string][]string{
+	"harvest": {"Date", "Client", "Project", "Task", "Notes", "Hours", "First name", "Last name"},
+	"toggl":   {"User", "Email", "Client", "Project", "Task", "Description", "Start date", "Start time", "Duration"},
+}
+
+// Every OUTPUT_FORMAT with the extension of its file when several formats are written in one run
+var outputFormatExtensions = map[string]string{
+	"text":     ".txt",
+	"json":     ".json",
+	"csv":      ".csv",
+	"markdown": ".md",
+	"harvest":  ".harvest.csv",
+	"toggl":    ".toggl.csv",
+}
+
+// A timelog within the reported window, kept with its exact time for timesheet exports
+type TimesheetEntry struct {
+	ProjectPath  string
+	MergeRequest bool
+	Username     string
+	IID          string
+	Title        string
+	SpentAt      time.Time
+	Hours        float32
+	// display name and public email of the user, only fetched for timesheet exports
+	Name  string
+	Email string
+}
+
+// Enough of the issue of an entry to classify it as dev or non dev time
+func (entry TimesheetEntry) issue() Issue {
+	return Issue{IID: entry.IID, Title: entry.Title, ProjectPath: entry.ProjectPath, MergeRequest: entry.MergeRequest}
+}
+
+// Collect timelogs within the window, for a single user or for everyone when username is empty
+func getTimesheetEntries(start time.Time, end time.Time, username string, timelogData *TimelogData, reportOptions ReportOptions, stats *RunStats) []TimesheetEntry {
+	var entries []TimesheetEntry
+	local := reportOptions.FilterLocation
+
+	stats.IssuesFetched += len(timelogData.Project.Issues.Nodes)
+	for _, issue := range timelogData.Project.Issues.Nodes {
+		stats.IssuesFiltered++
+		for _, timelog := range issue.Timelogs.Nodes {
+			stats.TimelogsScanned++
+
+			spentAt, _, ok := withinWindow(issue, timelog, start, end, local, stats)
+			if !ok {
+				continue
+			}
+			if username != "" && timelog.User.Username != username {
+				stats.Excluded["username"]++
+				continue
+			}
+
+			stats.TimelogsIncluded++
+			entries = append(entries, TimesheetEntry{issue.ProjectPath, issue.MergeRequest, timelog.User.Username, issue.IID, issue.Title, spentAt.In(reportOptions.OutputLocation), float32(timelog.TimeSpent) / 3600, timelog.User.Name, timelog.User.PublicEmail})
+		}
+	}
+
+	return entries
+}
+
+// Harvest imports a first and a last name, the name is split on its first space and the username stands in for a missing one
+func splitName(name string, username string) (string, string) {
+	if name = strings.TrimSpace(name); name == "" {
+		return username, ""
+	}
+	first, last, _ := strings.Cut(name, " ")
+
+	return first, strings.TrimSpace(last)
+}
+
+// Write entries as a CSV matching the import format of Harvest or Toggl
+// Harvest has no tags, a RUN_TAG follows the issue reference in the notes, Toggl gets it in a Tags column
+func writeTimesheet(w io.Writer, format string, client string, runTag string, entries []TimesheetEntry) error {
+	csvWriter := csv.NewWriter(w)
+	header := timesheetFormats[format]
+	if format == "toggl" && runTag != "" {
+		header = append(header[:len(header):len(header)], "Tags")
+	}
+	if err := csvWriter.Write(header); err != nil {
+		return err
+	}
+
+	warnedEmails := make(map[string]bool)
+	for _, entry := range entries {
+		var record []string
+		switch format {
+		case "harvest":
+			firstName, lastName := splitName(entry.Name, entry.Username)
+			notes := referencePrefix(entry.MergeRequest) + entry.IID
+			if runTag != "" {
+				notes += " [" + runTag + "]"
+			}
+			record = []string{
+				entry.SpentAt.Format("2006-01-02"),
+				client,
+				entry.ProjectPath,
+				entry.Title,
+				notes,
+				fmt.Sprintf("%.2f", entry.Hours),
+				firstName,
+				lastName,
+			}
+		case "toggl":
+			// a correction has no duration of its own, Toggl would reject the whole import
+			if entry.Hours < 0 {
+				logger.Warn("Skipping negative timelog, Toggl durations can not be negative", "issue", entry.ProjectPath+referencePrefix(entry.MergeRequest)+entry.IID, "username", entry.Username, "hours", entry.Hours)
+				continue
+			}
+			if entry.Email == "" && !warnedEmails[entry.Username] {
+				warnedEmails[entry.Username] = true
+				logger.Warn("User has no public email, Toggl needs one to import their entries", "username", entry.Username)
+			}
+			seconds := int(math.Round(float64(entry.Hours) * 3600))
+			record = []string{
+				entry.Username,
+				entry.Email,
+				client,
+				entry.ProjectPath,
+				entry.Title,
+				fmt.Sprintf("%s%s: %s", referencePrefix(entry.MergeRequest), entry.IID, entry.Title),
+				entry.SpentAt.Format("2006-01-02"),
+				entry.SpentAt.Format("15:04:05"),
+				fmt.Sprintf("%02d:%02d:%02d", seconds/3600, seconds/60%60, seconds%60),
+			}
+			if runTag != "" {
+				record = append(record, runTag)
+			}
+		}
+		if err := csvWriter.Write(record); err != nil {
+			return err
+		}
+	}
+
+	csvWriter.Flush()
+	return csvWriter.Error()
+}
+
+// kind tells an issue from a merge request sharing its iid, issue or merge_request
+var csvReportHeader = []string{"project", "issue_iid", "issue_title", "username", "hours", "spent_at", "category", "kind"}
+
+// Write one row per timelog with its dev or non dev category, mixed issues giving a row for each part,
+// followed after a blank line by a section of per user totals
+func writeCSVReport(w io.Writer, entries []TimesheetEntry, trackingIssues []string, trackingPattern *regexp.Regexp, reportOptions ReportOptions) error {
+	// RUN_TAG adds a last column, left out when not set so the columns stay the documented ones
+	withRunTag := func(record []string, value string) []string {
+		if reportOptions.RunTag != "" {
+			return append(record[:len(record):len(record)], value)
+		}
+		return record
+	}
+
+	hours := func(h float32) string {
+		if reportOptions.CSVDecimalComma {
+			return strings.Replace(fmt.Sprintf("%.2f", h), ".", ",", 1)
+		}
+		return fmt.Sprintf("%.2f", h)
+	}
+
+	if reportOptions.ExcelBOM {
+		if _, err := io.WriteString(w, "\uFEFF"); err != nil {
+			return err
+		}
+	}
+	csvWriter := csv.NewWriter(w)
+	if reportOptions.CSVDelimiter != 0 {
+		csvWriter.Comma = reportOptions.CSVDelimiter
+	}
+	if err := csvWriter.Write(withRunTag(csvReportHeader, "run_tag")); err != nil {
+		return err
+	}
+
+	devHoursPerUser := make(map[string]float32)
+	nonDevHoursPerUser := make(map[string]float32)
+	for _, entry := range entries {
+		devFraction, ok := reportOptions.MixedIssues.devFraction(entry.issue())
+		if !ok {
+			devFraction = 1
+			if _, isTracking := trackingCategory(entry.issue(), trackingIssues, trackingPattern); isTracking {
+				devFraction = 0
+			}
+		}
+
+		parts := []struct {
+			category string
+			hours    float32
+		}{
+			{"dev", entry.Hours * devFraction},
+			{"non-dev", entry.Hours * (1 - devFraction)},
+		}
+		kind := "issue"
+		if entry.MergeRequest {
+			kind = "merge_request"
+		}
+		for _, part := range parts {
+			if part.hours == 0 {
+				continue
+			}
+			record := []string{
+				entry.ProjectPath,
+				entry.IID,
+				entry.Title,
+				entry.Username,
+				hours(part.hours),
+				entry.SpentAt.Format("2006-01-02"),
+				part.category,
+				kind,
+			}
+			if err := csvWriter.Write(withRunTag(record, reportOptions.RunTag)); err != nil {
+				return err
+			}
+		}
+		devHoursPerUser[entry.Username] += entry.Hours * devFraction
+		nonDevHoursPerUser[entry.Username] += entry.Hours * (1 - devFraction)
+	}
+
+	usernames := make([]string, 0, len(devHoursPerUser))
+	for username := range devHoursPerUser {
+		usernames = append(usernames, username)
+	}
+	sort.Strings(usernames)
+
+	// an empty record is written as a blank line
+	if err := csvWriter.Write(nil); err != nil {
+		return err
+	}
+	if err := csvWriter.Write(withRunTag([]string{"username", "dev_hours", "non_dev_hours", "hours"}, "run_tag")); err != nil {
+		return err
+	}
+	for _, username := range usernames {
+		record := []string{
+			username,
+			hours(devHoursPerUser[username]),
+			hours(nonDevHoursPerUser[username]),
+			hours(devHoursPerUser[username] + nonDevHoursPerUser[username]),
+		}
+		if err := csvWriter.Write(withRunTag(record, reportOptions.RunTag)); err != nil {
+			return err
+		}
+	}
+
+	csvWriter.Flush()
+	return csvWriter.Error()
+}
+
+// Total hours of a user in the JSON report, all users reports also split them between dev and non dev
+type UserSpentTime struct {
+	Username    string   `json:"username"`
+	UserID      int64    `json:"user_id,omitempty"`
+	Hours       float32  `json:"hours"`
+	DevHours    *float32 `json:"dev_hours,omitempty"`
+	NonDevHours *float32 `json:"non_dev_hours,omitempty"`
+}
+
+// Single JSON document printed on stdout by OUTPUT_FORMAT=json
+type SpentTimeReport struct {
+	StartDate string          `json:"start_date"`
+	EndDate   string          `json:"end_date"`
+	RunTag    string          `json:"run_tag,omitempty"`
+	Users     []UserSpentTime `json:"users"`
+	Entries   []DetailEntry   `json:"entries"`
+	// non dev hours per reporting issue, all users report only
+	NonDevCategories map[string]float32 `json:"non_dev_categories,omitempty"`
+	// hours of the negative timelogs, already subtracted from the users hours
+	CorrectionsTotal float32 `json:"corrections_total"`
+}
+
+func writeSpentTimeReport(w io.Writer, report SpentTimeReport) error {
+	if report.Users == nil {
+		report.Users = []UserSpentTime{}
+	}
+	if report.Entries == nil {
+		report.Entries = []DetailEntry{}
+	}
+
+	return json.NewEncoder(w).Encode(report)
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"flag"
+	"os"
+)
+
+// Command-line flags, each one sets the env var it stands for, the token is only read from GITLAB_TOKEN
+var envFlags = []struct {
+	name  string
+	env   string
+	usage string
+}{
+	{"project", "GITLAB_PROJECT_PATH", "project full path, comma-separated for several projects"},
+	{"days", "DAYS_NUM", "number of previous days to look into"},
+	{"start", "START_DATE", "first day of the report window, YYYY-MM-DD"},
+	{"end", "END_DATE", "last day of the report window, YYYY-MM-DD"},
+	{"reporting-issue", "GITLAB_REPORTING_ISSUE", "title part of the issues counted as non dev time, comma-separated for several categories"},
+	{"host", "GITLAB_HOST", "gitlab host"},
+	{"baseline", "BASELINE_FILE", "JSON report of an earlier run to print the hours difference with"},
+	{"color", "COLOR", "color the text report: auto (stdout, or OUTPUT_FILE, is a terminal and NO_COLOR is unset), always or never"},
+	{"config", "CONFIG_FILE", "YAML or .toml config file keyed by env var names, flags and env vars win over it"},
+}
+
+// Boolean flags, set to true when passed without a value
+var envBoolFlags = []struct {
+	name  string
+	env   string
+	usage string
+}{
+	{"all-users", "ALL_USERS", "report on every user instead of the token owner"},
+	{"show-zero", "SHOW_ZERO", "print issues whose hours net to zero after corrections"},
+	{"no-cache", "NO_CACHE", "fetch from Gitlab without reading or writing the CACHE_TTL cache"},
+	{"refresh", "CACHE_REFRESH", "fetch from Gitlab and replace the CACHE_TTL cache"},
+	{"print-query", "PRINT_QUERY", "print each GraphQL query and its variables to stderr"},
+	{"dry-run", "DRY_RUN", "print the first GraphQL query of each project and exit without sending it"},
+}
+
+// Flags are applied to the environment before the env files are loaded, godotenv then never overrides them
+func applyFlags(args []string) error {
+	flagSet := flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	for _, envFlag := range envFlags {
+		flagSet.String(envFlag.name, "", envFlag.usage+" (overrides "+envFlag.env+")")
+	}
+	boolValues := make(map[string]*bool)
+	for _, envFlag := range envBoolFlags {
+		boolValues[envFlag.name] = flagSet.Bool(envFlag.name, false, envFlag.usage+" (overrides "+envFlag.env+")")
+	}
+	if err := flagSet.Parse(args); err != nil {
+		return err
+	}
+
+	var err error
+	flagSet.Visit(func(f *flag.Flag) {
+		if err != nil {
+			return
+		}
+		for _, envFlag := range envBoolFlags {
+			if envFlag.name == f.Name {
+				// an empty value still counts as set for godotenv, so -all-users=false still wins over the env files
+				boolEnv := ""
+				if *boolValues[f.Name] {
+					boolEnv = "true"
+				}
+				err = os.Setenv(envFlag.env, boolEnv)
+				return
+			}
+		}
+		for _, envFlag := range envFlags {
+			if envFlag.name == f.Name {
+				err = os.Setenv(envFlag.env, f.Value.String())
+			}
+		}
+	})
+
+	return err
+}
@@ -0,0 +1,76 @@
+package main
+
+// Translations of the fixed report labels, data values are never translated
+var translations = map[string]map[string]string{
+	"fr": {
+		"Total spent time from %s to %s for %s : %.1fh": "Temps total passé du %s au %s pour %s : %.1fh",
+		"-- Total dev time spent --":                    "-- Temps total de dev --",
+		"-- Total NON dev time spent--":                 "-- Temps total hors dev --",
+		"-- NON dev time per category --":               "-- Temps hors dev par catégorie --",
+		"from %s to %s for %s : %.1fh":                  "du %s au %s pour %s : %.1fh",
+		"Total : %.1fh":                                 "Total : %.1fh",
+		"-- Capacity --":                                "-- Capacité --",
+		"-- Total time spent per epic --":               "-- Temps total par epic --",
+		"-- Total time spent per milestone --":          "-- Temps total par jalon --",
+		"-- Total time spent per iteration --":          "-- Temps total par itération --",
+		"-- Time per issue --":                          "-- Temps par ticket --",
+		"Corrections : %.1fh":                           "Corrections : %.1fh",
+		"-- Issues per user --":                         "-- Tickets par utilisateur --",
+		"-- NON dev share of the time spent --":         "-- Part du temps hors dev --",
+		"-- Time per day --":                            "-- Temps par jour --",
+		"-- Time per week --":                           "-- Temps par semaine --",
+		"-- Stats --":                                   "-- Statistiques --",
+		"-- Budget --":                                  "-- Budget --",
+		"-- Spent time vs estimate --":                  "-- Temps passé et estimation --",
+		"-- Issues without estimate --":                 "-- Tickets sans estimation --",
+		"-- Team time per date --":                      "-- Temps de l'équipe par date --",
+		"-- Open issues with less than %.0f%% of their estimate logged --": "-- Tickets ouverts dont moins de %.0f%% de l'estimation est saisi --",
+		"-- Daily hours stats --":                                "-- Statistiques des heures par jour --",
+		"-- Billable dev time --":                                "-- Temps de dev facturable --",
+		"-- Compared with %s to %s --":                           "-- Comparaison avec le %s au %s --",
+		"-- Notes vs timelogs --":                                "-- Notes et temps saisis --",
+		"-- Issues with logged time but not updated since %s --": "-- Tickets avec du temps saisi mais pas mis à jour depuis le %s --",
+		"others (%d users) : %.1fh":                              "autres (%d utilisateurs) : %.1fh",
+		"other issues: %.1fh (%d issues)":                        "autres tickets : %.1fh (%d tickets)",
+	},
+	"de": {
+		"Total spent time from %s to %s for %s : %.1fh": "Gesamte aufgewendete Zeit vom %s bis %s für %s : %.1fh",
+		"-- Total dev time spent --":                    "-- Gesamte Entwicklungszeit --",
+		"-- Total NON dev time spent--":                 "-- Gesamte Nicht-Entwicklungszeit --",
+		"-- NON dev time per category --":               "-- Nicht-Entwicklungszeit pro Kategorie --",
+		"from %s to %s for %s : %.1fh":                  "vom %s bis %s für %s : %.1fh",
+		"Total : %.1fh":                                 "Gesamt : %.1fh",
+		"-- Capacity --":                                "-- Kapazität --",
+		"-- Total time spent per epic --":               "-- Gesamtzeit pro Epic --",
+		"-- Total time spent per milestone --":          "-- Gesamtzeit pro Meilenstein --",
+		"-- Total time spent per iteration --":          "-- Gesamtzeit pro Iteration --",
+		"-- Time per issue --":                          "-- Zeit pro Issue --",
+		"Corrections : %.1fh":                           "Korrekturen : %.1fh",
+		"-- Issues per user --":                         "-- Issues pro Benutzer --",
+		"-- NON dev share of the time spent --":         "-- Anteil der Nicht-Entwicklungszeit --",
+		"-- Time per day --":                            "-- Zeit pro Tag --",
+		"-- Time per week --":                           "-- Zeit pro Woche --",
+		"-- Stats --":                                   "-- Statistiken --",
+		"-- Budget --":                                  "-- Budget --",
+		"-- Spent time vs estimate --":                  "-- Aufgewendete Zeit und Schätzung --",
+		"-- Issues without estimate --":                 "-- Issues ohne Schätzung --",
+		"-- Team time per date --":                      "-- Teamzeit pro Datum --",
+		"-- Open issues with less than %.0f%% of their estimate logged --": "-- Offene Issues mit weniger als %.0f%% ihrer Schätzung erfasst --",
+		"-- Daily hours stats --":                                "-- Statistik der Stunden pro Tag --",
+		"-- Billable dev time --":                                "-- Abrechenbare Entwicklungszeit --",
+		"-- Compared with %s to %s --":                           "-- Verglichen mit %s bis %s --",
+		"-- Notes vs timelogs --":                                "-- Notizen und Zeiteinträge --",
+		"-- Issues with logged time but not updated since %s --": "-- Issues mit erfasster Zeit, aber seit %s nicht aktualisiert --",
+		"others (%d users) : %.1fh":                              "andere (%d Benutzer) : %.1fh",
+		"other issues: %.1fh (%d issues)":                        "andere Issues: %.1fh (%d Issues)",
+	},
+}
+
+// Translate a fixed label, falling back to english when there is no translation
+func translate(lang string, label string) string {
+	if translated, ok := translations[lang][label]; ok {
+		return translated
+	}
+
+	return label
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+)
+
+// Diagnostics logger, the report itself is printed through the log package and never filtered by LOG_LEVEL
+var logger = slog.New(slog.NewTextHandler(os.Stderr, nil))
+
+var logLevels = map[string]slog.Level{
+	"debug": slog.LevelDebug,
+	"info":  slog.LevelInfo,
+	"warn":  slog.LevelWarn,
+	"error": slog.LevelError,
+}
+
+func newLogger(w io.Writer, level string, format string) (*slog.Logger, error) {
+	if level == "" {
+		level = "info"
+	}
+	logLevel, ok := logLevels[level]
+	if !ok {
+		return nil, fmt.Errorf("LOG_LEVEL must be one of debug, info, warn or error")
+	}
+
+	options := &slog.HandlerOptions{Level: logLevel}
+	switch format {
+	case "", "text":
+		return slog.New(slog.NewTextHandler(w, options)), nil
+	case "json":
+		return slog.New(slog.NewJSONHandler(w, options)), nil
+	}
+
+	return nil, fmt.Errorf("LOG_FORMAT must be text or json")
+}
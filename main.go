@@ -2,208 +2,3383 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
+	"math"
+	"math/rand"
+	"net/http"
+	"net/url"
 	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/joho/godotenv"
 	graphql "github.com/machinebox/graphql"
 	gitlab "github.com/xanzy/go-gitlab"
+	"golang.org/x/oauth2"
+	"golang.org/x/sync/errgroup"
 )
 
+type Timelog struct {
+	TimeSpent int    `json:"timeSpent"`
+	SpentAt   string `json:"spentAt"`
+	// note of the /spend quick action, absent for time added with the time tracking form or the API
+	Note *struct {
+		CreatedAt string `json:"createdAt"`
+	} `json:"note"`
+	User struct {
+		ID       string `json:"id"`
+		Username string `json:"username"`
+		// only fetched for the harvest and toggl exports, the email is empty unless the user made it public
+		Name        string `json:"name"`
+		PublicEmail string `json:"publicEmail"`
+	} `json:"user"`
+}
+
+// Key used to aggregate a timelog per user, the numeric id survives username changes
+func userKey(timelog Timelog, keyBy string) string {
+	if keyBy == "id" {
+		return strings.TrimPrefix(timelog.User.ID, "gid://gitlab/User/")
+	}
+
+	return timelog.User.Username
+}
+
+// Numeric id of the timelog user for the JSON report, 0 when Gitlab sent none
+func userID(timelog Timelog) int64 {
+	id, _ := strconv.ParseInt(strings.TrimPrefix(timelog.User.ID, "gid://gitlab/User/"), 10, 64)
+	return id
+}
+
+type Issue struct {
+	// global id, unique across projects
+	ID        string `json:"id"`
+	IID       string `json:"iid"`
+	Title     string `json:"title"`
+	UpdatedAt string `json:"updatedAt"`
+	State     string `json:"state"`
+	// empty while the issue is open, only fetched for issues
+	ClosedAt string `json:"closedAt"`
+	Weight   *int   `json:"weight"`
+	// seconds, over the whole issue life and not only the reported window
+	TimeEstimate   int `json:"timeEstimate"`
+	TotalTimeSpent int `json:"totalTimeSpent"`
+	Epic           *struct {
+		Title string `json:"title"`
+	} `json:"epic"`
+	Milestone *struct {
+		Title string `json:"title"`
+	} `json:"milestone"`
+	Iteration *Iteration `json:"iteration"`
+	MovedTo   *struct {
+		ID string `json:"id"`
+	} `json:"movedTo"`
+	// only fetched with TIMEOFF_LABEL
+	Labels *struct {
+		Nodes []struct {
+			Title string `json:"title"`
+		} `json:"nodes"`
+	} `json:"labels"`
+	Timelogs struct {
+		PageInfo PageInfo  `json:"pageInfo"`
+		Nodes    []Timelog `json:"nodes"`
+	} `json:"timelogs"`
+	// not part of the query, set after fetching so issues of several projects can be told apart
+	ProjectPath string `json:"-"`
+	// merge requests are fetched separately and reported as issues
+	MergeRequest bool `json:"-"`
+	// full reference like group/project#12, only fetched for the issues of a group
+	Reference string `json:"reference"`
+}
+
+func (issue Issue) hasLabel(title string) bool {
+	if issue.Labels == nil {
+		return false
+	}
+	for _, label := range issue.Labels.Nodes {
+		if label.Title == title {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Iterations of an automatic cadence have no title, their dates tell them apart
+type Iteration struct {
+	Title     string `json:"title"`
+	StartDate string `json:"startDate"`
+	DueDate   string `json:"dueDate"`
+}
+
+func (iteration Iteration) label() string {
+	if iteration.Title != "" {
+		return iteration.Title
+	}
+	return fmt.Sprintf("%s - %s", iteration.StartDate, iteration.DueDate)
+}
+
+type PageInfo struct {
+	HasNextPage bool   `json:"hasNextPage"`
+	EndCursor   string `json:"endCursor"`
+}
+
 type TimelogData struct {
 	Project struct {
 		Issues struct {
-			Nodes []struct {
-				IID      string `json:"iid"`
-				Title    string `json:"title"`
-				Timelogs struct {
-					Nodes []struct {
-						TimeSpent int    `json:"timeSpent"`
-						SpentAt   string `json:"spentAt"`
-						User      struct {
-							Username string `json:"username"`
-						} `json:"user"`
-					} `json:"nodes"`
-				} `json:"timelogs"`
-			} `json:"nodes"`
+			PageInfo PageInfo `json:"pageInfo"`
+			Nodes    []Issue  `json:"nodes"`
 		} `json:"issues"`
 	} `json:"project"`
+	// number of GraphQL requests needed to fetch every page
+	PagesFetched int `json:"-"`
 }
 
-func getTimelogs(projectId string, apiToken string, client *graphql.Client, ctx context.Context) (*TimelogData, error) {
-	// Construct the GraphQL query
-	req := graphql.NewRequest(`
-		query($fullPath: ID!) {
-			project(fullPath: $fullPath) {
-				issues {
-					nodes {
-						iid
-						title
-						timelogs {
-							nodes {
-								timeSpent
-								spentAt
-								user {
-									username
-								}
-							}
-						}
-					}
-				}
-			}
-		}
-		`)
+// Expected hours per username for the reported window, users without an entry use the default
+type Capacities struct {
+	Default float32
+	PerUser map[string]float32
+}
 
-	req.Var("fullPath", projectId)
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", apiToken))
+func loadCapacities(path string, defaultCapacity float32) (*Capacities, error) {
+	capacities := &Capacities{Default: defaultCapacity, PerUser: make(map[string]float32)}
+	if path == "" {
+		return capacities, nil
+	}
 
-	var data TimelogData
-	if err := client.Run(ctx, req, &data); err != nil {
+	content, err := os.ReadFile(path)
+	if err != nil {
 		return nil, err
 	}
 
-	return &data, nil
+	if err := json.Unmarshal(content, &capacities.PerUser); err != nil {
+		return nil, fmt.Errorf("could not parse %s: %w", path, err)
+	}
+
+	return capacities, nil
 }
 
-func getUserSpentTime(daysNum int, username string, timelogData *TimelogData) {
+func (c *Capacities) get(username string) (float32, bool) {
+	capacity, ok := c.PerUser[username]
+	if !ok {
+		capacity = c.Default
+	}
 
-	var totalSpentTime float32
-	date := time.Now().AddDate(0, 0, -daysNum).Format("2006-01-02")
-	local, _ := time.LoadLocation("Local")
+	return capacity, capacity > 0
+}
 
-	for _, issue := range timelogData.Project.Issues.Nodes {
-		for _, timelog := range issue.Timelogs.Nodes {
+func logCapacity(capacities *Capacities, username string, spentTime float32, timeOff float32) {
+	if capacities == nil {
+		return
+	}
+
+	capacity, ok := capacities.get(username)
+	if !ok {
+		log.Printf("No capacity set for %s", username)
+		return
+	}
+	// time off is not worked time, it lowers the hours the user could log instead
+	capacity -= timeOff
+	line := fmt.Sprintf("Capacity for %s : %.1fh / %.1fh", username, spentTime, capacity)
+	if capacity > 0 {
+		line += fmt.Sprintf(" (%.0f%%)", spentTime/capacity*100)
+	}
+	if timeOff > 0 {
+		line += fmt.Sprintf(", %.1fh time off", timeOff)
+	}
+	log.Println(line)
+}
+
+// Counters describing what a run fetched and filtered out, printed as a footer when SHOW_STATS is set
+type RunStats struct {
+	PagesFetched     int
+	IssuesFetched    int
+	IssuesFiltered   int
+	TimelogsScanned  int
+	TimelogsIncluded int
+	DetailLines      int
+	Excluded         map[string]int
+}
+
+// Excluded key of the timelogs whose spentAt could not be parsed, also reported without SHOW_STATS
+const malformedSpentAt = "malformed spentAt"
+
+func newRunStats() *RunStats {
+	return &RunStats{Excluded: make(map[string]int)}
+}
+
+func logStats(stats *RunStats, reportOptions ReportOptions) {
+	log.Println(sectionHeader(translate(reportOptions.Language, "-- Stats --")))
+	log.Printf("Pages fetched : %d", stats.PagesFetched)
+	log.Printf("Issues fetched : %d", stats.IssuesFetched)
+	log.Printf("Issues after filters : %d", stats.IssuesFiltered)
+	log.Printf("Timelogs scanned : %d", stats.TimelogsScanned)
+	log.Printf("Timelogs included : %d", stats.TimelogsIncluded)
+	log.Printf("Detail lines printed : %d", stats.DetailLines)
+
+	filters := make([]string, 0, len(stats.Excluded))
+	for filter := range stats.Excluded {
+		filters = append(filters, filter)
+	}
+	sort.Strings(filters)
+	for _, filter := range filters {
+		log.Printf("Timelogs excluded by %s : %d", filter, stats.Excluded[filter])
+	}
+}
+
+// GITLAB_HOST is often set without a scheme, which makes the derived API URLs malformed
+func normalizeGitlabHost(host string) (string, error) {
+	if !strings.Contains(host, "://") {
+		logger.Info("GITLAB_HOST has no scheme, using https", "host", "https://"+host)
+		host = "https://" + host
+	}
+
+	hostUrl, err := url.Parse(host)
+	if err != nil {
+		return "", err
+	}
+	if hostUrl.Scheme != "http" && hostUrl.Scheme != "https" {
+		return "", fmt.Errorf("unsupported scheme %q", hostUrl.Scheme)
+	}
+	if hostUrl.Host == "" {
+		return "", fmt.Errorf("missing host name in %q", host)
+	}
+
+	return strings.TrimSuffix(hostUrl.String(), "/"), nil
+}
+
+// Number of previous days covered by a PERIOD anchor, relative to now in the day boundary timezone
+//   - week-to-date: from the most recent Monday (today when it is Monday)
+//   - month-to-date: from the 1st of the current month
+//   - year-to-date: from January 1st of the current year
+func periodDaysNum(period string, now time.Time) (int, error) {
+	switch period {
+	case "week-to-date":
+		return (int(now.Weekday()) + 6) % 7, nil
+	case "month-to-date":
+		return now.Day() - 1, nil
+	case "year-to-date":
+		return now.YearDay() - 1, nil
+	}
+
+	return 0, fmt.Errorf("unknown period %q", period)
+}
+
+// Sets the User-Agent of every request, machinebox/graphql has no option for it
+type userAgentTransport struct {
+	userAgent string
+	next      http.RoundTripper
+}
+
+func (t *userAgentTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set("User-Agent", t.userAgent)
+
+	return t.next.RoundTrip(req)
+}
+
+// Returned instead of sending the query with DRY_RUN, run then stops without an error
+var errDryRun = errors.New("dry run, the query was not sent")
+
+// Stand-ins for what a dry run would have asked Gitlab, the token owner and the SCOPE=my-projects projects
+const (
+	dryRunUsername    = "(token owner)"
+	dryRunProjectPath = "(member projects)"
+)
+
+// Print the query and variables of each GraphQL request to stderr, ready to paste in GraphiQL
+// The token only travels in the Authorization header, the body is redacted anyway before it is printed
+type printQueryTransport struct {
+	w       io.Writer
+	dryRun  bool
+	secrets []string
+	next    http.RoundTripper
+}
+
+func (t *printQueryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		var request struct {
+			Query     string          `json:"query"`
+			Variables json.RawMessage `json:"variables"`
+		}
+		err = json.NewDecoder(body).Decode(&request)
+		body.Close()
+		if err != nil {
+			return nil, err
+		}
+		// a single write so the requests of concurrent projects do not interleave
+		fmt.Fprint(t.w, redact(fmt.Sprintf("GraphQL query:\n%s\nGraphQL variables:\n%s\n", strings.TrimSpace(request.Query), request.Variables), t.secrets))
+	}
+	if t.dryRun {
+		return nil, errDryRun
+	}
+
+	return t.next.RoundTrip(req)
+}
+
+// The GraphQL client does not look at the response status, so transient failures (network errors, 5xx and 429) are retried
+// here with an exponential backoff, other statuses and GraphQL errors are returned at once. The webhook post reuses it
+type retryTransport struct {
+	retries   int
+	baseDelay time.Duration
+	next      http.RoundTripper
+}
 
-			// When selecting dates only, Gitlab will set the time to midnight local time
-			// So it might fail to load timelogs for today as it can be minus few hours and lose one day (depending on the timezone)
-			spentAt, _ := time.Parse(time.RFC3339, timelog.SpentAt)
-			localSpentAt := spentAt.In(local).Format("2006-01-02")
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		attemptReq := req.Clone(req.Context())
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			attemptReq.Body = body
+		}
 
-			if localSpentAt >= date && timelog.User.Username == username {
-				totalSpentTime += float32(timelog.TimeSpent) / 3600
-				log.Printf("%.1fh at %s - #%s: %s\n", float32(timelog.TimeSpent)/3600, localSpentAt, issue.IID, issue.Title)
+		res, err := t.next.RoundTrip(attemptReq)
+		var reason string
+		switch {
+		case err != nil:
+			if req.Context().Err() != nil {
+				return nil, err
 			}
+			reason = err.Error()
+		case res.StatusCode == http.StatusTooManyRequests || res.StatusCode >= 500:
+			reason = res.Status
+		default:
+			return res, nil
+		}
+		if attempt >= t.retries {
+			return res, err
+		}
+		if res != nil {
+			io.Copy(io.Discard, res.Body)
+			res.Body.Close()
+		}
+
+		// full delay doubles on each attempt, half of it is random so parallel runs do not retry in step
+		delay := t.baseDelay << attempt
+		delay = delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+		logger.Warn("Request failed, retrying", "reason", reason, "delay", delay.Round(time.Millisecond), "attempt", attempt+1, "retries", t.retries)
+		select {
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		case <-time.After(delay):
 		}
 	}
-	log.Printf("Total spent time since %s for %s : %.1fh", date, username, totalSpentTime)
 }
 
-func getAllUsersSpentTime(daysNum int, trackingIssue string, timelogData *TimelogData) {
-	// store a map of username = total spent time on tickets
-	totalDevTimePerUser := make(map[string]float32)
-	totalNonDevTimePerUser := make(map[string]float32)
+// Fewer remaining requests than this pause every request until the rate limit resets, a margin for the concurrent fetches
+const rateLimitThreshold = 10
 
-	date := time.Now().AddDate(0, 0, -daysNum).Format("2006-01-02")
-	local, _ := time.LoadLocation("Local")
+// Gitlab sends RateLimit-Remaining and RateLimit-Reset on every response, throttling on them avoids hitting 429s
+type rateLimitTransport struct {
+	next http.RoundTripper
 
-	for _, issue := range timelogData.Project.Issues.Nodes {
-		for _, timelog := range issue.Timelogs.Nodes {
+	// shared by the concurrent fetches
+	mu       sync.Mutex
+	resumeAt time.Time
+}
 
-			// When selecting dates only, Gitlab will set the time to midnight local time
-			// So it might fail to load timelogs for today as it can be minus few hours and lose one day (depending on the timezone)
-			spentAt, _ := time.Parse(time.RFC3339, timelog.SpentAt)
-			localSpentAt := spentAt.In(local).Format("2006-01-02")
+func (t *rateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.mu.Lock()
+	wait := time.Until(t.resumeAt)
+	t.mu.Unlock()
+	if wait > 0 {
+		select {
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		case <-time.After(wait):
+		}
+	}
 
-			if localSpentAt >= date {
-				if strings.Contains(issue.Title, trackingIssue) {
-					totalNonDevTimePerUser[timelog.User.Username] += float32(timelog.TimeSpent) / 3600
-				} else {
-					totalDevTimePerUser[timelog.User.Username] += float32(timelog.TimeSpent) / 3600
-				}
-				log.Printf("%.1fh at %s by %s - #%s: %s\n", float32(timelog.TimeSpent)/3600, localSpentAt, timelog.User.Username, issue.IID, issue.Title)
-			}
+	res, err := t.next.RoundTrip(req)
+	if err != nil {
+		return res, err
+	}
+
+	remaining, errRemaining := strconv.Atoi(res.Header.Get("RateLimit-Remaining"))
+	reset, errReset := strconv.ParseInt(res.Header.Get("RateLimit-Reset"), 10, 64)
+	if errRemaining == nil && errReset == nil && remaining < rateLimitThreshold {
+		// reset is a unix timestamp
+		resumeAt := time.Unix(reset, 0)
+		t.mu.Lock()
+		if resumeAt.After(t.resumeAt) && time.Until(resumeAt) > 0 {
+			t.resumeAt = resumeAt
+			logger.Warn("Gitlab rate limit almost reached, pausing requests until it resets", "remaining", remaining, "resumeAt", resumeAt.Format(time.RFC3339))
 		}
+		t.mu.Unlock()
 	}
 
-	log.Println("-- Total dev time spent --")
+	return res, nil
+}
+
+// Transport shared by the GraphQL and REST clients, going through HTTPS_PROXY / HTTP_PROXY and trusting caCertFile on top of the system roots
+func newBaseTransport(caCertFile string, insecureSkipVerify bool) (http.RoundTripper, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = http.ProxyFromEnvironment
+	if caCertFile == "" && !insecureSkipVerify {
+		return transport, nil
+	}
 
-	var totalDevSpentTime float32
-	for username, time := range totalDevTimePerUser {
-		log.Printf("since %s for %s : %.1fh", date, username, time)
-		totalDevSpentTime += time
+	tlsConfig := &tls.Config{InsecureSkipVerify: insecureSkipVerify}
+	if caCertFile != "" {
+		pem, err := os.ReadFile(caCertFile)
+		if err != nil {
+			return nil, err
+		}
+		roots, err := x509.SystemCertPool()
+		if err != nil {
+			roots = x509.NewCertPool()
+		}
+		if !roots.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no PEM certificate found in %s", caCertFile)
+		}
+		tlsConfig.RootCAs = roots
 	}
+	transport.TLSClientConfig = tlsConfig
 
-	log.Printf("Total : %.1fh", totalDevSpentTime)
+	return transport, nil
+}
 
-	log.Println("-- Total NON dev time spent--")
-	var totalNonDevSpentTime float32
-	for username, time := range totalNonDevTimePerUser {
-		log.Printf("since %s for %s : %.1fh", date, username, time)
-		totalNonDevSpentTime += time
+// GraphQL client for an endpoint, the fetch functions only depend on it and not on where it points
+func newGraphQLClient(endpoint string, userAgent string, maxRetries int, transport http.RoundTripper, printQuery *printQueryTransport) *graphql.Client {
+	var clientTransport http.RoundTripper = &retryTransport{
+		retries:   maxRetries,
+		baseDelay: time.Second,
+		next:      &rateLimitTransport{next: &userAgentTransport{userAgent: userAgent, next: transport}},
+	}
+	// outermost so a query is printed once whatever the retries, and a dry run never reaches them
+	if printQuery != nil {
+		printQuery.next = clientTransport
+		clientTransport = printQuery
+	}
+	client := graphql.NewClient(endpoint, graphql.WithHTTPClient(&http.Client{Transport: clientTransport}))
+	// The client logs the query, variables, headers and raw response, only the variables and the response size are kept
+	client.Log = func(s string) {
+		switch {
+		case strings.HasPrefix(s, ">> variables: "):
+			logger.Debug("GraphQL request", "variables", strings.TrimPrefix(s, ">> variables: "))
+		case strings.HasPrefix(s, "<< "):
+			logger.Debug("GraphQL response", "bytes", len(s)-len("<< "))
+		}
 	}
 
-	log.Printf("Total : %.1fh", totalNonDevSpentTime)
+	return client
 }
 
-func main() {
-	err := godotenv.Load()
+// Dev fraction (0.0 to 1.0) of issues mixing dev and non dev time, keyed by iid or project/path#iid
+type MixedIssues map[string]float32
+
+func loadMixedIssues(path string) (MixedIssues, error) {
+	content, err := os.ReadFile(path)
 	if err != nil {
-		log.Printf("Could not load .env file, error: %s", err)
+		return nil, err
 	}
 
-	// Check env vars
-	apiToken := os.Getenv("GITLAB_TOKEN")
-	if apiToken == "" {
-		log.Fatal("GITLAB_TOKEN environment variable is not set")
+	var mixedIssues MixedIssues
+	if err := json.Unmarshal(content, &mixedIssues); err != nil {
+		return nil, fmt.Errorf("could not parse %s: %w", path, err)
+	}
+	for key, devFraction := range mixedIssues {
+		if devFraction < 0 || devFraction > 1 {
+			return nil, fmt.Errorf("dev fraction of %s must be between 0.0 and 1.0, got %v", key, devFraction)
+		}
 	}
 
-	projectId := os.Getenv("GITLAB_PROJECT_PATH")
-	if projectId == "" {
-		log.Fatal("GITLAB_PROJECT_PATH environment variable is not set")
+	return mixedIssues, nil
+}
+
+func (m MixedIssues) devFraction(issue Issue) (float32, bool) {
+	if issue.MergeRequest {
+		return 0, false
+	}
+	if devFraction, ok := m[issue.ProjectPath+"#"+issue.IID]; ok {
+		return devFraction, true
 	}
+	devFraction, ok := m[issue.IID]
 
-	gitlabHost := os.Getenv("GITLAB_HOST")
-	if gitlabHost == "" {
-		gitlabHost = "https://gitlab.com"
-		log.Printf("GITLAB_HOST is not set, using default %s", gitlabHost)
+	return devFraction, ok
+}
+
+// Split a comma-separated env var, ignoring blank items
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
 	}
 
-	daysEnv := os.Getenv("DAYS_NUM")
-	if daysEnv == "" {
-		daysEnv = "0"
-		log.Printf("DAYS_NUM is not set, using default %s", daysEnv)
+	return items
+}
+
+// Optional parts of the timelogs query
+type QueryOptions struct {
+	// name of the GraphQL operation, shown in Gitlab logs
+	OperationName string
+	// epic, weight and iteration are only available on Gitlab EE
+	WithEpic      bool
+	WithWeight    bool
+	WithMilestone bool
+	WithIteration bool
+	WithLabels    bool
+	// name and public email of the timelog users, the timesheet imports need them
+	WithUserDetails bool
+	// only issues of the milestones with these titles
+	Milestones []string
+	// only issues updated within these RFC3339 bounds when set, one chunk of CHUNK_BY
+	UpdatedAfter  string
+	UpdatedBefore string
+	// opened, closed or all, timelogs of closed issues are kept whether logged before or after closing
+	State string
+	// only issues of the iteration with this title, current for the iteration running today
+	Iteration string
+	// only issues assigned to this username, whoever logged the time
+	Assignee string
+	// free text search on issue titles and descriptions, not on timelogs
+	Search string
+	// issues must have all Labels and none of ExcludeLabels
+	Labels        []string
+	ExcludeLabels []string
+	// when timelogs were recorded, needed to report what was added since a timestamp
+	WithRecordedAt bool
+	// where issues were moved to, so timelogs copied on the new issue are not counted twice
+	WithMovedTo bool
+	// the full path is a group, its issues are those of every project of the group and its subgroups
+	Group bool
+}
+
+// Labels of an issue or merge request, only needed to find the time off
+const labelsField = `
+						labels {
+							nodes {
+								title
+							}
+						}`
+
+// Gitlab caps connections to 100 nodes, both issues and their timelogs are fetched page by page
+const pageSize = 100
+
+func timelogNodeFields(queryOptions QueryOptions) string {
+	fields := `
+								timeSpent
+								spentAt
+								user {
+									id
+									username%s
+								}`
+	userFields := ""
+	if queryOptions.WithUserDetails {
+		userFields = `
+									name
+									publicEmail`
+	}
+	fields = fmt.Sprintf(fields, userFields)
+	if queryOptions.WithRecordedAt {
+		fields += `
+								note {
+									createdAt
+								}`
 	}
 
-	daysNum, err := strconv.Atoi(daysEnv)
-	if err != nil {
-		log.Fatal("DAYS_NUM must be in integer, it represents the number of previous days to fetch issues for")
+	return fields
+}
+
+func getTimelogs(projectId string, apiToken string, queryOptions QueryOptions, client *graphql.Client, ctx context.Context) (*TimelogData, error) {
+	var data TimelogData
+	after := ""
+	issuePages := 0
+	for {
+		page, err := getIssuesPage(projectId, apiToken, queryOptions, after, client, ctx)
+		if err != nil {
+			// Gitlab CE does not know the epic and weight fields, fetch again without them rather than failing
+			if queryOptions.WithEpic && strings.Contains(err.Error(), "epic") {
+				logger.Warn("Epics are not available on this Gitlab instance, all issues will be reported without epic")
+				queryOptions.WithEpic = false
+				continue
+			}
+			if queryOptions.WithIteration && strings.Contains(err.Error(), "iteration") {
+				logger.Warn("Iterations are not available on this Gitlab instance, all issues will be reported without iteration")
+				queryOptions.WithIteration = false
+				continue
+			}
+			if queryOptions.WithWeight && strings.Contains(err.Error(), "weight") {
+				logger.Warn("Weights are not available on this Gitlab instance, all issues will be reported without weight")
+				queryOptions.WithWeight = false
+				continue
+			}
+			return nil, err
+		}
+		data.PagesFetched++
+		issuePages++
+
+		for _, issue := range page.Project.Issues.Nodes {
+			issue.ProjectPath = projectId
+			if queryOptions.Group {
+				issue.ProjectPath = referenceProjectPath(issue.Reference)
+			}
+			if issue.Timelogs.PageInfo.HasNextPage {
+				pages, err := getRemainingTimelogs(issue.ProjectPath, apiToken, queryOptions, "issue", &issue, client, ctx)
+				if err != nil {
+					return nil, err
+				}
+				data.PagesFetched += pages
+			}
+			data.Project.Issues.Nodes = append(data.Project.Issues.Nodes, issue)
+		}
+
+		// large projects take many pages, without this the fetch looks hung, LOG_LEVEL=warn silences it
+		// one line per page rather than a rewritten TTY line, projects are fetched concurrently
+		logger.Info("Fetched page", "project", projectId, "page", issuePages, "issues", len(data.Project.Issues.Nodes))
+		if !page.Project.Issues.PageInfo.HasNextPage {
+			return &data, nil
+		}
+		after = page.Project.Issues.PageInfo.EndCursor
 	}
+}
 
-	getAllUsers := os.Getenv("ALL_USERS")
-	reportingIssue := os.Getenv("GITLAB_REPORTING_ISSUE")
+// Project path of a full issue reference, group/project#12 gives group/project
+func referenceProjectPath(reference string) string {
+	if i := strings.LastIndex(reference, "#"); i >= 0 {
+		return reference[:i]
+	}
 
-	gitlabAPIUrl := gitlabHost + "/api/v4"
-	gitlabGraphQLUrl := gitlabHost + "/api/graphql"
+	return reference
+}
 
-	// Get current username with the personal access token
-	gitlabClient, err := gitlab.NewClient(apiToken, gitlab.WithBaseURL(gitlabAPIUrl))
-	if err != nil {
-		log.Fatalf("Failed to create client: %v", err)
+func getIssuesPage(projectId string, apiToken string, queryOptions QueryOptions, after string, client *graphql.Client, ctx context.Context) (*TimelogData, error) {
+	var issueFields string
+	if queryOptions.WithEpic {
+		issueFields += `
+						epic {
+							title
+						}`
+	}
+	if queryOptions.WithWeight {
+		issueFields += `
+						weight`
+	}
+	if queryOptions.WithMovedTo {
+		issueFields += `
+						movedTo {
+							id
+						}`
+	}
+	if queryOptions.WithMilestone {
+		issueFields += `
+						milestone {
+							title
+						}`
+	}
+	if queryOptions.WithIteration {
+		issueFields += `
+						iteration {
+							title
+							startDate
+							dueDate
+						}`
+	}
+	if queryOptions.WithLabels {
+		issueFields += labelsField
+	}
+	// Group issues come from several projects, the reference tells which one
+	namespace := "project"
+	if queryOptions.Group {
+		issueFields += `
+						reference(full: true)`
+		namespace = "project: group"
 	}
 
-	currentUser, _, err := gitlabClient.Users.CurrentUser()
-	if err != nil {
-		log.Fatalf("Failed to get current user: %v", err)
+	// Issue filters are applied server side and combined with AND
+	queryVars := "$fullPath: ID!, $first: Int, $after: String"
+	issueFilters := []string{"first: $first", "after: $after"}
+	vars := map[string]interface{}{"fullPath": projectId, "first": pageSize}
+	if after != "" {
+		vars["after"] = after
+	}
+	if queryOptions.Group {
+		issueFilters = append(issueFilters, "includeSubgroups: true")
+	}
+	if queryOptions.State != "" {
+		queryVars += ", $state: IssuableState"
+		issueFilters = append(issueFilters, "state: $state")
+		vars["state"] = queryOptions.State
+	}
+	if queryOptions.UpdatedAfter != "" {
+		queryVars += ", $updatedAfter: Time"
+		issueFilters = append(issueFilters, "updatedAfter: $updatedAfter")
+		vars["updatedAfter"] = queryOptions.UpdatedAfter
+	}
+	if queryOptions.UpdatedBefore != "" {
+		queryVars += ", $updatedBefore: Time"
+		issueFilters = append(issueFilters, "updatedBefore: $updatedBefore")
+		vars["updatedBefore"] = queryOptions.UpdatedBefore
+	}
+	// an issue assigned to several users matches any of them
+	if queryOptions.Assignee != "" {
+		queryVars += ", $assigneeUsernames: [String!]"
+		issueFilters = append(issueFilters, "assigneeUsernames: $assigneeUsernames")
+		vars["assigneeUsernames"] = []string{queryOptions.Assignee}
+	}
+	if len(queryOptions.Milestones) > 0 {
+		queryVars += ", $milestoneTitle: [String]"
+		issueFilters = append(issueFilters, "milestoneTitle: $milestoneTitle")
+		vars["milestoneTitle"] = queryOptions.Milestones
+	}
+	// Gitlab resolves the iteration whose dates contain today itself
+	if queryOptions.Iteration == "current" {
+		issueFilters = append(issueFilters, "iterationWildcardId: CURRENT")
+	} else if queryOptions.Iteration != "" {
+		queryVars += ", $iterationTitle: String"
+		issueFilters = append(issueFilters, "iterationTitle: $iterationTitle")
+		vars["iterationTitle"] = queryOptions.Iteration
+	}
+	if queryOptions.Search != "" {
+		queryVars += ", $search: String"
+		issueFilters = append(issueFilters, "search: $search")
+		vars["search"] = queryOptions.Search
+	}
+	if len(queryOptions.Labels) > 0 {
+		queryVars += ", $labelName: [String]"
+		issueFilters = append(issueFilters, "labelName: $labelName")
+		vars["labelName"] = queryOptions.Labels
+	}
+	if len(queryOptions.ExcludeLabels) > 0 {
+		queryVars += ", $notLabelName: [String!]"
+		issueFilters = append(issueFilters, "not: { labelName: $notLabelName }")
+		vars["notLabelName"] = queryOptions.ExcludeLabels
 	}
 
-	// Gitlab REST API does not provide timelog object on issues with who log what, only the graphQL API does that
-	graphQLClient := graphql.NewClient(gitlabGraphQLUrl)
+	// Construct the GraphQL query
+	req := graphql.NewRequest(fmt.Sprintf(`
+		query %s(%s) {
+			%s(fullPath: $fullPath) {
+				issues(%s) {
+					pageInfo {
+						hasNextPage
+						endCursor
+					}
+					nodes {
+						id
+						iid
+						title
+						updatedAt
+						state
+						closedAt
+						timeEstimate
+						totalTimeSpent%s
+						timelogs(first: %d) {
+							pageInfo {
+								hasNextPage
+								endCursor
+							}
+							nodes {%s
+							}
+						}
+					}
+				}
+			}
+		}
+		`, queryOptions.OperationName, queryVars, namespace, strings.Join(issueFilters, ", "), issueFields, pageSize, timelogNodeFields(queryOptions)))
 
-	// Get go context
-	ctx := context.Background()
+	for key, value := range vars {
+		req.Var(key, value)
+	}
+	if apiToken != "" {
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", apiToken))
+	}
 
-	timelogData, err := getTimelogs(projectId, apiToken, graphQLClient, ctx)
-	if err != nil {
-		log.Fatalf("Failed to execute query: %v", err)
+	// decoded in two steps to tell a null project from one without issues in the window
+	var response struct {
+		Project json.RawMessage `json:"project"`
+	}
+	if err := client.Run(ctx, req, &response); err != nil {
+		return nil, err
+	}
+	if len(response.Project) == 0 || string(response.Project) == "null" {
+		return nil, errProjectNotFound
+	}
+	var data TimelogData
+	if err := json.Unmarshal(response.Project, &data.Project); err != nil {
+		return nil, err
 	}
 
-	if getAllUsers == "" {
-		getUserSpentTime(daysNum, currentUser.Username, timelogData)
-	} else {
-		getAllUsersSpentTime(daysNum, reportingIssue, timelogData)
+	return &data, nil
+}
+
+// Append the timelogs past the first page of an issue or merge request, field being issue or mergeRequest, returns the number of pages fetched
+func getRemainingTimelogs(projectId string, apiToken string, queryOptions QueryOptions, field string, issue *Issue, client *graphql.Client, ctx context.Context) (int, error) {
+	var pages int
+	for issue.Timelogs.PageInfo.HasNextPage {
+		req := graphql.NewRequest(fmt.Sprintf(`
+		query %s($fullPath: ID!, $iid: String!, $first: Int, $after: String) {
+			project(fullPath: $fullPath) {
+				node: %s(iid: $iid) {
+					timelogs(first: $first, after: $after) {
+						pageInfo {
+							hasNextPage
+							endCursor
+						}
+						nodes {%s
+						}
+					}
+				}
+			}
+		}
+		`, queryOptions.OperationName, field, timelogNodeFields(queryOptions)))
+
+		req.Var("fullPath", projectId)
+		req.Var("iid", issue.IID)
+		req.Var("first", pageSize)
+		req.Var("after", issue.Timelogs.PageInfo.EndCursor)
+		if apiToken != "" {
+			req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", apiToken))
+		}
+
+		var data struct {
+			Project struct {
+				Node struct {
+					Timelogs struct {
+						PageInfo PageInfo  `json:"pageInfo"`
+						Nodes    []Timelog `json:"nodes"`
+					} `json:"timelogs"`
+				} `json:"node"`
+			} `json:"project"`
+		}
+		if err := client.Run(ctx, req, &data); err != nil {
+			return pages, fmt.Errorf("could not fetch timelogs of %s%s: %w", referencePrefix(issue.MergeRequest), issue.IID, err)
+		}
+		pages++
+
+		issue.Timelogs.Nodes = append(issue.Timelogs.Nodes, data.Project.Node.Timelogs.Nodes...)
+		issue.Timelogs.PageInfo = data.Project.Node.Timelogs.PageInfo
+	}
+
+	return pages, nil
+}
+
+// Gitlab shows the timelogs of deleted accounts under this user
+const ghostUsername = "ghost"
+
+// Drop the timelogs of bot and system users from every issue, returns how many were dropped
+func excludeUsersTimelogs(timelogData *TimelogData, usernames []string) int {
+	excluded := 0
+	for i := range timelogData.Project.Issues.Nodes {
+		issue := &timelogData.Project.Issues.Nodes[i]
+		kept := issue.Timelogs.Nodes[:0]
+		for _, timelog := range issue.Timelogs.Nodes {
+			if slices.Contains(usernames, timelog.User.Username) {
+				excluded++
+				continue
+			}
+			kept = append(kept, timelog)
+		}
+		issue.Timelogs.Nodes = kept
+	}
+
+	return excluded
+}
+
+// Drop issues fetched twice, and when following moves, issues whose timelogs were copied on an issue that was fetched too
+func dedupeIssues(issues []Issue, followMoved bool) []Issue {
+	issuesByID := make(map[string]Issue)
+	for _, issue := range issues {
+		issuesByID[issue.ID] = issue
+	}
+
+	var dedupedIssues []Issue
+	seen := make(map[string]bool)
+	for _, issue := range issues {
+		if seen[issue.ID] {
+			continue
+		}
+		seen[issue.ID] = true
+
+		if followMoved && movedToFetchedIssue(issue, issuesByID) {
+			logger.Info("Skipping issue moved to an issue that is reported too", "issue", issue.ProjectPath+"#"+issue.IID)
+			continue
+		}
+		dedupedIssues = append(dedupedIssues, issue)
 	}
+
+	return dedupedIssues
+}
+
+// Follow the chain of moves within the fetched issues, a cycle only keeps its smallest id so its time is counted once
+func movedToFetchedIssue(issue Issue, issuesByID map[string]Issue) bool {
+	visited := map[string]bool{issue.ID: true}
+	current := issue
+	for current.MovedTo != nil {
+		next, ok := issuesByID[current.MovedTo.ID]
+		if !ok {
+			break
+		}
+
+		if visited[next.ID] {
+			if next.ID != issue.ID {
+				return true
+			}
+			for id := range visited {
+				if id < issue.ID {
+					return true
+				}
+			}
+			return false
+		}
+		visited[next.ID] = true
+		current = next
+	}
+
+	return current.ID != issue.ID
+}
+
+// Usernames expected to log time, either the members of the projects, of the group when set, or an explicit list
+func getExpectedUsernames(membersSource string, usernames string, projectPaths []string, groupPath string, gitlabClient *gitlab.Client) ([]string, error) {
+	if membersSource == "usernames" {
+		return splitList(usernames), nil
+	}
+	if groupPath != "" {
+		return getGroupMemberUsernames(groupPath, gitlabClient)
+	}
+
+	seen := make(map[string]bool)
+	var expectedUsernames []string
+	for _, projectPath := range projectPaths {
+		options := &gitlab.ListProjectMembersOptions{ListOptions: gitlab.ListOptions{PerPage: 100}}
+		for {
+			members, resp, err := gitlabClient.ProjectMembers.ListAllProjectMembers(projectPath, options)
+			if err != nil {
+				return nil, fmt.Errorf("could not list members of %s: %w", projectPath, err)
+			}
+			for _, member := range members {
+				if !seen[member.Username] {
+					seen[member.Username] = true
+					expectedUsernames = append(expectedUsernames, member.Username)
+				}
+			}
+
+			if resp.NextPage == 0 {
+				break
+			}
+			options.Page = resp.NextPage
+		}
+	}
+	sort.Strings(expectedUsernames)
+
+	return expectedUsernames, nil
+}
+
+// Members of a group, including those inherited from its parent groups
+func getGroupMemberUsernames(groupPath string, gitlabClient *gitlab.Client) ([]string, error) {
+	var usernames []string
+	options := &gitlab.ListGroupMembersOptions{ListOptions: gitlab.ListOptions{PerPage: 100}}
+	for {
+		members, resp, err := gitlabClient.Groups.ListAllGroupMembers(groupPath, options)
+		if err != nil {
+			return nil, fmt.Errorf("could not list members of %s: %w", groupPath, err)
+		}
+		for _, member := range members {
+			usernames = append(usernames, member.Username)
+		}
+
+		if resp.NextPage == 0 {
+			break
+		}
+		options.Page = resp.NextPage
+	}
+	sort.Strings(usernames)
+
+	return usernames, nil
+}
+
+// List the path of every project the authenticated user is a member of
+func getMemberProjectPaths(gitlabClient *gitlab.Client) ([]string, error) {
+	var projectPaths []string
+	options := &gitlab.ListProjectsOptions{
+		ListOptions: gitlab.ListOptions{PerPage: 100},
+		Membership:  gitlab.Bool(true),
+		Simple:      gitlab.Bool(true),
+	}
+
+	for {
+		projects, resp, err := gitlabClient.Projects.ListProjects(options)
+		if err != nil {
+			return nil, err
+		}
+		for _, project := range projects {
+			projectPaths = append(projectPaths, project.PathWithNamespace)
+		}
+
+		if resp.NextPage == 0 {
+			return projectPaths, nil
+		}
+		options.Page = resp.NextPage
+	}
+}
+
+// Gitlab GraphQL API sometimes returns an empty issues set under load, retry a few times before accepting it
+func getTimelogsRetryOnEmpty(retries int, delay time.Duration, projectId string, apiToken string, queryOptions QueryOptions, client *graphql.Client, ctx context.Context, stats *RunStats) (*TimelogData, error) {
+	timelogData, err := getTimelogs(projectId, apiToken, queryOptions, client, ctx)
+	for attempt := 1; err == nil && attempt <= retries && len(timelogData.Project.Issues.Nodes) == 0; attempt++ {
+		stats.PagesFetched += timelogData.PagesFetched
+		logger.Warn("No issues returned, retrying", "delay", delay, "attempt", attempt, "retries", retries)
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(delay):
+		}
+		timelogData, err = getTimelogs(projectId, apiToken, queryOptions, client, ctx)
+	}
+	if err == nil {
+		stats.PagesFetched += timelogData.PagesFetched
+	}
+
+	return timelogData, err
+}
+
+// First day of each month after start up to end, they split the issues on updatedAt into a chunk per month of the window
+func monthBoundaries(start time.Time, end time.Time) []time.Time {
+	var boundaries []time.Time
+	for month := time.Date(start.Year(), start.Month()+1, 1, 0, 0, 0, 0, start.Location()); !month.After(end); month = month.AddDate(0, 1, 0) {
+		boundaries = append(boundaries, month)
+	}
+
+	return boundaries
+}
+
+// Query options of each chunk, the first and last ones are open ended so every issue falls in a chunk whenever it was updated
+func chunkQueryOptions(queryOptions QueryOptions, boundaries []time.Time) []QueryOptions {
+	chunks := make([]QueryOptions, len(boundaries)+1)
+	for i := range chunks {
+		chunks[i] = queryOptions
+		if i > 0 {
+			chunks[i].UpdatedAfter = boundaries[i-1].Format(time.RFC3339)
+		}
+		if i < len(boundaries) {
+			chunks[i].UpdatedBefore = boundaries[i].Format(time.RFC3339)
+		}
+	}
+
+	return chunks
+}
+
+// Fetch the issues, and merge requests when asked, of every project, at most concurrency queries at a time
+// Chunk boundaries fetch the issues of each project in several chunks of updatedAt, each with its own cursor pagination
+func fetchProjectsTimelogs(projectPaths []string, chunkBoundaries []time.Time, concurrency int, retryOnEmpty int, withMergeRequests bool, apiToken string, queryOptions QueryOptions, cache *fileCache, client *graphql.Client, ctx context.Context) (*TimelogData, error) {
+	type projectResult struct {
+		projectPath  string
+		issues       []Issue
+		pagesFetched int
+	}
+	chunks := chunkQueryOptions(queryOptions, chunkBoundaries)
+	// an empty chunk is expected when no issue was updated in its month, only a single chunk is retried on empty
+	chunkRetryOnEmpty := retryOnEmpty
+	if len(chunks) > 1 {
+		chunkRetryOnEmpty = 0
+	}
+
+	results := make([]projectResult, len(projectPaths))
+	// Chunks and merge requests are fetched concurrently, each goroutine only writes its own result, merged in chunk order
+	fetched := make([][]projectResult, len(projectPaths))
+	group, groupCtx := errgroup.WithContext(ctx)
+	group.SetLimit(concurrency)
+	for i, projectPath := range projectPaths {
+		i, projectPath := i, projectPath
+		results[i].projectPath = projectPath
+		if cache != nil {
+			if issues, fetchedAt, ok := cache.load(projectPath, queryOptions, withMergeRequests); ok {
+				logger.Info("Served from cache", "project", projectPath, "fetchedAt", fetchedAt.Format(time.RFC3339))
+				results[i].issues = issues
+				continue
+			}
+		}
+
+		// merge requests come last, after the issue chunks
+		fetched[i] = make([]projectResult, len(chunks)+1)
+		for c, chunkOptions := range chunks {
+			c, chunkOptions := c, chunkOptions
+			group.Go(func() error {
+				chunkStats := newRunStats()
+				chunkTimelogData, err := getTimelogsRetryOnEmpty(chunkRetryOnEmpty, 2*time.Second, projectPath, apiToken, chunkOptions, client, groupCtx, chunkStats)
+				if err != nil {
+					return fmt.Errorf("Failed to execute query for %s: %w", projectPath, err)
+				}
+				fetched[i][c] = projectResult{issues: chunkTimelogData.Project.Issues.Nodes, pagesFetched: chunkStats.PagesFetched}
+				return nil
+			})
+		}
+		if withMergeRequests {
+			group.Go(func() error {
+				mergeRequestData, err := getMergeRequestTimelogs(projectPath, apiToken, queryOptions, client, groupCtx)
+				if err != nil {
+					return fmt.Errorf("Failed to execute merge requests query for %s: %w", projectPath, err)
+				}
+				fetched[i][len(chunks)] = projectResult{issues: mergeRequestData.Project.MergeRequests.Nodes, pagesFetched: mergeRequestData.PagesFetched}
+				return nil
+			})
+		}
+	}
+	if err := group.Wait(); err != nil {
+		return nil, err
+	}
+
+	for i, projectFetched := range fetched {
+		// served from the cache
+		if projectFetched == nil {
+			continue
+		}
+		for _, chunk := range projectFetched {
+			results[i].issues = append(results[i].issues, chunk.issues...)
+			results[i].pagesFetched += chunk.pagesFetched
+		}
+		// an issue updated while its project was fetched can be in two chunks
+		if len(chunks) > 1 {
+			results[i].issues = dedupeIssues(results[i].issues, false)
+		}
+
+		if cache != nil {
+			if err := cache.store(results[i].projectPath, queryOptions, withMergeRequests, results[i].issues); err != nil {
+				logger.Warn("Could not write cache file", "project", results[i].projectPath, "error", err)
+			} else {
+				logger.Info("Fetched and cached", "project", results[i].projectPath)
+			}
+		}
+	}
+
+	// Sorted by project path so the output does not depend on which project was fetched first
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].projectPath < results[j].projectPath
+	})
+	timelogData := &TimelogData{}
+	for _, result := range results {
+		timelogData.PagesFetched += result.pagesFetched
+		timelogData.Project.Issues.Nodes = append(timelogData.Project.Issues.Nodes, result.issues...)
+	}
+	timelogData.Project.Issues.Nodes = dedupeIssues(timelogData.Project.Issues.Nodes, queryOptions.WithMovedTo)
+
+	return timelogData, nil
+}
+
+// Options shared by the report functions
+type ReportOptions struct {
+	Capacities   *Capacities
+	VerifyTotals bool
+	// timezone of the day boundaries used to filter and group timelogs
+	FilterLocation *time.Location
+	OutputLocation *time.Location
+	// empty to keep the default formatting, half-up or bankers
+	RoundingMode string
+	// language of the fixed labels: en, fr or de
+	Language string
+	// print when issues were last updated and flag the ones not updated within the window
+	ShowUpdatedAt bool
+	// username or id, how the all users report aggregates users
+	KeyBy string
+	// issues with less time are summed into a single line of the per issue report
+	IssueDetailMinHours float32
+	// issues split between dev and non dev time
+	MixedIssues MixedIssues
+	// sum timelogs of the same user on the same issue and day into one detail line
+	CollapseEntries bool
+	// empty to print the detail lines as fetched, all or user to sort them by spentAt with a running total of every line or of each user's
+	Cumulative string
+	// print a single JSON document on stdout instead of the text report
+	JSONOutput bool
+	RunTag     string
+	// more than one project is reported, issues are referenced with their project path
+	MultipleProjects bool
+	// with a scheme and no trailing slash, issue links are built from it
+	GitlabHost string
+	// where json, csv, markdown and timesheet reports are written, stdout unless OUTPUT_FILE is set
+	Output io.Writer
+	// daily to print the single user hours per day, with days without logs too when ShowEmptyDays is set
+	Breakdown     string
+	ShowEmptyDays bool
+	// username or hours, order of the users in the all users report
+	SortBy string
+	// only print the users with the most hours in each section of the all users report, 0 for everyone
+	TopN int
+	// when set, the all users report only counts these users and prints them even without logs
+	Usernames []string
+	// print detail and issue lines whose hours net to zero after corrections
+	ShowZero bool
+	// csv report column separator, a comma when zero, and hours printed with a decimal comma
+	CSVDelimiter    rune
+	CSVDecimalComma bool
+	// start the csv report with a UTF-8 byte order mark, without it Excel reads accented names as its locale's encoding, EXCEL_BOM or CSV_BOM
+	ExcelBOM bool
+	// hours of issues with this label are time off, left out of the user and all users totals and subtracted from the capacity
+	TimeOffLabel string
+	// append a sparkline of the daily hours of the window to the user total lines
+	Sparkline bool
+}
+
+// Round hours to the displayed tenth, bankers rounds .x5 to the nearest even tenth to avoid a bias over many totals
+func roundHours(hours float32, mode string) float32 {
+	// float32 hours are rarely exact, snap them first so 0.25h is really seen as a tie
+	tenths := math.Round(float64(hours)*10*1e6) / 1e6
+
+	switch mode {
+	case "half-up":
+		return float32(math.Round(tenths) / 10)
+	case "bankers":
+		return float32(math.RoundToEven(tenths) / 10)
+	}
+
+	return hours
+}
+
+// Float sums of a timelog and its correction can miss an exact zero, anything under a second is none
+func isZeroHours(hours float32) bool {
+	return math.Abs(float64(hours)) < 0.5/3600
+}
+
+// A detail line of the report, one per timelog unless entries are collapsed
+type DetailEntry struct {
+	ProjectPath  string    `json:"project_path,omitempty"`
+	MergeRequest bool      `json:"merge_request,omitempty"`
+	Username     string    `json:"username"`
+	UserID       int64     `json:"user_id,omitempty"`
+	IID          string    `json:"iid"`
+	Title        string    `json:"title"`
+	Date         string    `json:"date"`
+	Hours        float32   `json:"hours"`
+	URL          string    `json:"url,omitempty"`
+	ClosedAt     string    `json:"closed_at,omitempty"`
+	UpdatedAt    time.Time `json:"-"`
+	SpentAt      time.Time `json:"-"`
+}
+
+// Midnight of the day a time falls on in loc, so days compare with Before and After whatever the hour
+func localDay(t time.Time, loc *time.Location) time.Time {
+	t = t.In(loc)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+}
+
+// Whether spentAt falls within the inclusive window, comparing days in the filter timezone
+func inWindow(spentAt time.Time, start time.Time, end time.Time, loc *time.Location) bool {
+	day := localDay(spentAt, loc)
+	return !day.Before(localDay(start, loc)) && !day.After(localDay(end, loc))
+}
+
+// A malformed spentAt would be read as year 1 and silently fall out of the window
+func parseSpentAt(issue Issue, timelog Timelog, stats *RunStats) (time.Time, bool) {
+	spentAt, err := time.Parse(time.RFC3339, timelog.SpentAt)
+	if err != nil {
+		logger.Warn("Skipping timelog with malformed spentAt", "issue", issue.ProjectPath+"#"+issue.IID, "spentAt", timelog.SpentAt)
+		stats.Excluded[malformedSpentAt]++
+		return time.Time{}, false
+	}
+
+	return spentAt, true
+}
+
+// Parsed spentAt and its day in loc when the timelog falls within the window, why it does not is counted in stats
+// When selecting dates only, Gitlab will set the time to midnight local time
+// So it might fail to load timelogs for today as it can be minus few hours and lose one day (depending on the timezone)
+func withinWindow(issue Issue, timelog Timelog, start time.Time, end time.Time, loc *time.Location, stats *RunStats) (time.Time, string, bool) {
+	spentAt, ok := parseSpentAt(issue, timelog, stats)
+	if !ok {
+		return time.Time{}, "", false
+	}
+	if !inWindow(spentAt, start, end, loc) {
+		stats.Excluded["date window"]++
+		return time.Time{}, "", false
+	}
+
+	return spentAt, spentAt.In(loc).Format("2006-01-02"), true
+}
+
+func newDetailEntry(issue Issue, timelog Timelog, spentAt time.Time, reportOptions ReportOptions) DetailEntry {
+	updatedAt, _ := time.Parse(time.RFC3339, issue.UpdatedAt)
+
+	entry := DetailEntry{
+		ProjectPath:  issue.ProjectPath,
+		MergeRequest: issue.MergeRequest,
+		Username:     timelog.User.Username,
+		UserID:       userID(timelog),
+		IID:          issue.IID,
+		Title:        issue.Title,
+		Date:         spentAt.In(reportOptions.OutputLocation).Format("2006-01-02"),
+		Hours:        float32(timelog.TimeSpent) / 3600,
+		UpdatedAt:    updatedAt,
+		SpentAt:      spentAt,
+	}
+	if reportOptions.GitlabHost != "" {
+		entry.URL = issueURL(reportOptions.GitlabHost, issue.ProjectPath, issue.IID, issue.MergeRequest)
+	}
+	if closedAt, err := time.Parse(time.RFC3339, issue.ClosedAt); err == nil {
+		entry.ClosedAt = closedAt.In(reportOptions.OutputLocation).Format("2006-01-02")
+	}
+
+	return entry
+}
+
+// Merge entries sharing user, issue and date, keeping the order of their first occurrence
+func collapseEntries(entries []DetailEntry) []DetailEntry {
+	index := make(map[string]int)
+	var collapsed []DetailEntry
+	for _, entry := range entries {
+		key := entry.Username + "\x00" + entry.ProjectPath + referencePrefix(entry.MergeRequest) + entry.IID + "\x00" + entry.Date
+		if i, ok := index[key]; ok {
+			collapsed[i].Hours += entry.Hours
+			continue
+		}
+		index[key] = len(collapsed)
+		collapsed = append(collapsed, entry)
+	}
+
+	return collapsed
+}
+
+// Gitlab references issues with # and merge requests with !
+func referencePrefix(mergeRequest bool) string {
+	if mergeRequest {
+		return "!"
+	}
+
+	return "#"
+}
+
+// Iids are only unique within a project, so they are prefixed with it when several projects are reported
+func issueRef(projectPath string, iid string, mergeRequest bool, reportOptions ReportOptions) string {
+	if reportOptions.MultipleProjects {
+		return projectPath + referencePrefix(mergeRequest) + iid
+	}
+
+	return referencePrefix(mergeRequest) + iid
+}
+
+// Link to an issue or merge request page, gitlabHost has a scheme and no trailing slash
+func issueURL(gitlabHost string, projectPath string, iid string, mergeRequest bool) string {
+	kind := "issues"
+	if mergeRequest {
+		kind = "merge_requests"
+	}
+
+	return fmt.Sprintf("%s/%s/-/%s/%s", gitlabHost, projectPath, kind, iid)
+}
+
+// URL appended to a text line, terminals supporting hyperlinks make it clickable
+func issueLink(projectPath string, iid string, mergeRequest bool, reportOptions ReportOptions) string {
+	if reportOptions.GitlabHost == "" {
+		return ""
+	}
+
+	return " " + issueURL(reportOptions.GitlabHost, projectPath, iid, mergeRequest)
+}
+
+func logDetails(entries []DetailEntry, reportOptions ReportOptions, withUsername bool, stats *RunStats) {
+	if reportOptions.CollapseEntries {
+		entries = collapseEntries(entries)
+	}
+	if reportOptions.Cumulative != "" {
+		// sorted on a copy, the report keeps its entries in the order of the json output
+		entries = slices.Clone(entries)
+		sort.SliceStable(entries, func(i, j int) bool {
+			return entries[i].SpentAt.Before(entries[j].SpentAt)
+		})
+	}
+
+	// running totals keyed by username, or by an empty key for a single total of every line
+	cumulativeHours := make(map[string]float32)
+	for _, entry := range entries {
+		var cumulative string
+		if reportOptions.Cumulative != "" {
+			key := ""
+			if reportOptions.Cumulative == "user" {
+				key = entry.Username
+			}
+			cumulativeHours[key] += entry.Hours
+			cumulative = fmt.Sprintf(" [cum %.1fh]", roundHours(cumulativeHours[key], reportOptions.RoundingMode))
+		}
+
+		if isZeroHours(entry.Hours) && !reportOptions.ShowZero {
+			continue
+		}
+		stats.DetailLines++
+
+		// Negative time is logged to fix a mistake, it is counted but should not read as work
+		var correction string
+		if entry.Hours < 0 {
+			correction = " (correction)"
+		}
+		var issueDates string
+		if reportOptions.ShowUpdatedAt {
+			issueDates = fmt.Sprintf(" (updated %s)", entry.UpdatedAt.In(reportOptions.OutputLocation).Format("2006-01-02"))
+		}
+		if entry.ClosedAt != "" {
+			issueDates += fmt.Sprintf(" (closed %s)", entry.ClosedAt)
+		}
+
+		link := issueLink(entry.ProjectPath, entry.IID, entry.MergeRequest, reportOptions)
+
+		if withUsername {
+			log.Printf("%.1fh%s%s at %s by %s - %s: %s%s%s\n", entry.Hours, cumulative, correction, entry.Date, entry.Username, issueRef(entry.ProjectPath, entry.IID, entry.MergeRequest, reportOptions), entry.Title, issueDates, link)
+		} else {
+			log.Printf("%.1fh%s%s at %s - %s: %s%s%s\n", entry.Hours, cumulative, correction, entry.Date, issueRef(entry.ProjectPath, entry.IID, entry.MergeRequest, reportOptions), entry.Title, issueDates, link)
+		}
+	}
+}
+
+// Issues with time logged within the window are normally updated within it too, unless only their timelogs were edited
+func logStaleIssues(entries []DetailEntry, start time.Time, reportOptions ReportOptions) {
+	seen := make(map[string]bool)
+	var staleEntries []DetailEntry
+	for _, entry := range entries {
+		key := entry.ProjectPath + referencePrefix(entry.MergeRequest) + entry.IID
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		if localDay(entry.UpdatedAt, reportOptions.FilterLocation).Before(localDay(start, reportOptions.FilterLocation)) {
+			staleEntries = append(staleEntries, entry)
+		}
+	}
+
+	if len(staleEntries) == 0 {
+		return
+	}
+
+	log.Printf(sectionHeader(translate(reportOptions.Language, "-- Issues with logged time but not updated since %s --")), start.Format("2006-01-02"))
+	for _, entry := range staleEntries {
+		log.Printf("%s: %s last updated at %s%s", issueRef(entry.ProjectPath, entry.IID, entry.MergeRequest, reportOptions), entry.Title, entry.UpdatedAt.In(reportOptions.OutputLocation).Format("2006-01-02"), issueLink(entry.ProjectPath, entry.IID, entry.MergeRequest, reportOptions))
+	}
+}
+
+// Time a single user logged within the window
+type UserReport struct {
+	Start      time.Time
+	End        time.Time
+	Username   string
+	TotalHours float32
+	// hours of the negative timelogs, already subtracted from the total
+	CorrectionsTotal float32
+	// hours of the TIMEOFF_LABEL issues, not part of the total
+	TimeOffHours float32
+	// day in the filter timezone = hours
+	HoursPerDay map[string]float32
+	// ISO week such as 2024-W03 = hours
+	HoursPerWeek map[string]float32
+	Entries      []DetailEntry
+	// numeric id of the user, 0 until a timelog of theirs is found
+	UserID int64
+}
+
+// ISO week of the day spentAt falls on in loc, late December days can belong to week 1 of the next year
+func isoWeek(spentAt time.Time, loc *time.Location) string {
+	year, week := spentAt.In(loc).ISOWeek()
+	return fmt.Sprintf("%d-W%02d", year, week)
+}
+
+// Zero padded weeks sort chronologically as strings
+func sortedWeeks(hoursPerWeek map[string]float32) []string {
+	weeks := make([]string, 0, len(hoursPerWeek))
+	for week := range hoursPerWeek {
+		weeks = append(weeks, week)
+	}
+	sort.Strings(weeks)
+
+	return weeks
+}
+
+func getUserSpentTime(start time.Time, end time.Time, username string, timelogData *TimelogData, reportOptions ReportOptions, stats *RunStats) UserReport {
+	report := UserReport{Start: start, End: end, Username: username, HoursPerDay: make(map[string]float32), HoursPerWeek: make(map[string]float32)}
+	local := reportOptions.FilterLocation
+
+	stats.IssuesFetched += len(timelogData.Project.Issues.Nodes)
+	for _, issue := range timelogData.Project.Issues.Nodes {
+		stats.IssuesFiltered++
+		for _, timelog := range issue.Timelogs.Nodes {
+			stats.TimelogsScanned++
+
+			spentAt, day, ok := withinWindow(issue, timelog, report.Start, report.End, local, stats)
+			if !ok {
+				continue
+			}
+			if timelog.User.Username != username {
+				stats.Excluded["username"]++
+				continue
+			}
+			if reportOptions.TimeOffLabel != "" && issue.hasLabel(reportOptions.TimeOffLabel) {
+				stats.Excluded["time off"]++
+				report.TimeOffHours += float32(timelog.TimeSpent) / 3600
+				continue
+			}
+
+			stats.TimelogsIncluded++
+			report.UserID = userID(timelog)
+			report.TotalHours += float32(timelog.TimeSpent) / 3600
+			if timelog.TimeSpent < 0 {
+				report.CorrectionsTotal += float32(timelog.TimeSpent) / 3600
+			}
+			report.HoursPerDay[day] += float32(timelog.TimeSpent) / 3600
+			report.HoursPerWeek[isoWeek(spentAt, local)] += float32(timelog.TimeSpent) / 3600
+			report.Entries = append(report.Entries, newDetailEntry(issue, timelog, spentAt, reportOptions))
+		}
+	}
+
+	return report
+}
+
+// JSON document of the single user report, printed by OUTPUT_FORMAT=json and served by SERVE_ADDR
+func userSpentTimeReport(report UserReport, reportOptions ReportOptions) SpentTimeReport {
+	entries := report.Entries
+	if reportOptions.CollapseEntries {
+		entries = collapseEntries(entries)
+	}
+
+	return SpentTimeReport{
+		StartDate: report.Start.Format("2006-01-02"),
+		EndDate:   report.End.Format("2006-01-02"),
+		RunTag:    reportOptions.RunTag,
+		Users:     []UserSpentTime{{Username: report.Username, UserID: report.UserID, Hours: roundHours(report.TotalHours, reportOptions.RoundingMode)}},
+		Entries:   entries,
+
+		CorrectionsTotal: roundHours(report.CorrectionsTotal, reportOptions.RoundingMode),
+	}
+}
+
+// A zero total either means nothing was logged or that nothing was fetched, the scanned counts tell them apart
+func logEmptyUserReport(report UserReport, stats *RunStats) {
+	logger.Info("No time logged in the window", "username", report.Username, "start", report.Start.Format("2006-01-02"), "end", report.End.Format("2006-01-02"), "issues_scanned", stats.IssuesFetched, "timelogs_scanned", stats.TimelogsScanned)
+	if stats.IssuesFetched == 0 {
+		logger.Warn("No issue was fetched, check the project path and the issue filters")
+	} else if stats.TimelogsScanned == 0 {
+		logger.Warn("None of the fetched issues has a timelog, time tracking may be disabled on the project or the token may lack the read_api scope")
+	}
+}
+
+func printUserReport(report UserReport, reportOptions ReportOptions, stats *RunStats) error {
+	if isZeroHours(report.TotalHours) {
+		logEmptyUserReport(report, stats)
+	}
+	if reportOptions.JSONOutput {
+		if err := writeSpentTimeReport(reportOptions.Output, userSpentTimeReport(report, reportOptions)); err != nil {
+			return err
+		}
+		logCapacity(reportOptions.Capacities, report.Username, report.TotalHours, report.TimeOffHours)
+		return nil
+	}
+
+	logDetails(report.Entries, reportOptions, false, stats)
+	if reportOptions.ShowUpdatedAt {
+		logStaleIssues(report.Entries, report.Start, reportOptions)
+	}
+	if reportOptions.Breakdown == "daily" {
+		log.Println(sectionHeader(translate(reportOptions.Language, "-- Time per day --")))
+		// walking the window keeps days in order, map iteration would not
+		for day := report.Start; !day.After(report.End); day = day.AddDate(0, 0, 1) {
+			hours, ok := report.HoursPerDay[day.Format("2006-01-02")]
+			if ok || reportOptions.ShowEmptyDays {
+				log.Printf("%s : %.1fh", day.Format("2006-01-02"), roundHours(hours, reportOptions.RoundingMode))
+			}
+		}
+	}
+	if reportOptions.Breakdown == "weekly" {
+		log.Println(sectionHeader(translate(reportOptions.Language, "-- Time per week --")))
+		for _, week := range sortedWeeks(report.HoursPerWeek) {
+			log.Printf("%s : %.1fh", week, roundHours(report.HoursPerWeek[week], reportOptions.RoundingMode))
+		}
+	}
+	log.Printf(translate(reportOptions.Language, "Total spent time from %s to %s for %s : %.1fh")+"%s", report.Start.Format("2006-01-02"), report.End.Format("2006-01-02"), report.Username, roundHours(report.TotalHours, reportOptions.RoundingMode), sparklineSuffix(report.Start, report.End, report.HoursPerDay, reportOptions))
+	if report.CorrectionsTotal != 0 {
+		log.Printf(translate(reportOptions.Language, "Corrections : %.1fh"), roundHours(report.CorrectionsTotal, reportOptions.RoundingMode))
+	}
+	logCapacity(reportOptions.Capacities, report.Username, report.TotalHours, report.TimeOffHours)
+
+	return nil
+}
+
+// Non dev issues match the reporting pattern when set, otherwise contain one of the reporting issue titles, the matched text is their category
+func trackingCategory(issue Issue, trackingIssues []string, trackingPattern *regexp.Regexp) (string, bool) {
+	if trackingPattern != nil {
+		match := trackingPattern.FindStringIndex(issue.Title)
+		if match == nil {
+			return "", false
+		}
+		return issue.Title[match[0]:match[1]], true
+	}
+
+	for _, trackingIssue := range trackingIssues {
+		if strings.Contains(issue.Title, trackingIssue) {
+			return trackingIssue, true
+		}
+	}
+
+	return "", false
+}
+
+// Time every user logged within the window, split between dev and non dev time, maps are keyed by user key
+type AllUsersReport struct {
+	Start       time.Time
+	End         time.Time
+	DevHours    map[string]float32
+	NonDevHours map[string]float32
+	TotalHours  map[string]float32
+	// category = total non dev time, the part of mixed issues is a category of its own
+	NonDevHoursPerCategory map[string]float32
+	// user key = username, to label and look up users keyed by id
+	Usernames map[string]string
+	// user key = numeric user id, requested users without logs have none
+	UserIDs map[string]int64
+	// sum of raw seconds, independent of classification, used to verify totals
+	GrandTotalSeconds int
+	// hours of the negative timelogs of every user, already subtracted from the totals
+	CorrectionsTotal float32
+	// user key = hours of the TIMEOFF_LABEL issues, not part of any total
+	TimeOffHours map[string]float32
+	// user key = number of distinct issues logged against, and the same over everyone
+	IssuesPerUser map[string]int
+	TotalIssues   int
+	// ISO week = user key = hours
+	HoursPerWeek map[string]map[string]float32
+	// user key = day in the filter timezone = hours, for the sparklines
+	DevHoursPerDay    map[string]map[string]float32
+	NonDevHoursPerDay map[string]map[string]float32
+	Entries           []DetailEntry
+}
+
+func getAllUsersSpentTime(start time.Time, end time.Time, trackingIssues []string, trackingPattern *regexp.Regexp, timelogData *TimelogData, reportOptions ReportOptions, stats *RunStats) AllUsersReport {
+	report := AllUsersReport{
+		Start:                  start,
+		End:                    end,
+		DevHours:               make(map[string]float32),
+		NonDevHours:            make(map[string]float32),
+		TotalHours:             make(map[string]float32),
+		NonDevHoursPerCategory: make(map[string]float32),
+		Usernames:              make(map[string]string),
+		UserIDs:                make(map[string]int64),
+		TimeOffHours:           make(map[string]float32),
+		IssuesPerUser:          make(map[string]int),
+		HoursPerWeek:           make(map[string]map[string]float32),
+		DevHoursPerDay:         make(map[string]map[string]float32),
+		NonDevHoursPerDay:      make(map[string]map[string]float32),
+	}
+	local := reportOptions.FilterLocation
+	// several timelogs of a user on one issue count as one issue
+	seenIssues := make(map[string]bool)
+	seenUserIssues := make(map[string]bool)
+	allowedUsernames := make(map[string]bool)
+	for _, username := range reportOptions.Usernames {
+		allowedUsernames[username] = true
+	}
+
+	stats.IssuesFetched += len(timelogData.Project.Issues.Nodes)
+	for _, issue := range timelogData.Project.Issues.Nodes {
+		stats.IssuesFiltered++
+		for _, timelog := range issue.Timelogs.Nodes {
+			stats.TimelogsScanned++
+
+			spentAt, day, ok := withinWindow(issue, timelog, report.Start, report.End, local, stats)
+			if !ok {
+				continue
+			}
+
+			if len(reportOptions.Usernames) > 0 && !allowedUsernames[timelog.User.Username] {
+				stats.Excluded["username"]++
+				continue
+			}
+			user := userKey(timelog, reportOptions.KeyBy)
+			if reportOptions.TimeOffLabel != "" && issue.hasLabel(reportOptions.TimeOffLabel) {
+				stats.Excluded["time off"]++
+				report.TimeOffHours[user] += float32(timelog.TimeSpent) / 3600
+				continue
+			}
+
+			stats.TimelogsIncluded++
+			report.Usernames[user] = timelog.User.Username
+			report.UserIDs[user] = userID(timelog)
+			if report.DevHoursPerDay[user] == nil {
+				report.DevHoursPerDay[user] = make(map[string]float32)
+				report.NonDevHoursPerDay[user] = make(map[string]float32)
+			}
+			if devFraction, ok := reportOptions.MixedIssues.devFraction(issue); ok {
+				report.DevHours[user] += float32(timelog.TimeSpent) / 3600 * devFraction
+				report.NonDevHours[user] += float32(timelog.TimeSpent) / 3600 * (1 - devFraction)
+				report.NonDevHoursPerCategory["mixed issues"] += float32(timelog.TimeSpent) / 3600 * (1 - devFraction)
+				report.DevHoursPerDay[user][day] += float32(timelog.TimeSpent) / 3600 * devFraction
+				report.NonDevHoursPerDay[user][day] += float32(timelog.TimeSpent) / 3600 * (1 - devFraction)
+			} else if category, ok := trackingCategory(issue, trackingIssues, trackingPattern); ok {
+				report.NonDevHours[user] += float32(timelog.TimeSpent) / 3600
+				report.NonDevHoursPerCategory[category] += float32(timelog.TimeSpent) / 3600
+				report.NonDevHoursPerDay[user][day] += float32(timelog.TimeSpent) / 3600
+			} else {
+				report.DevHours[user] += float32(timelog.TimeSpent) / 3600
+				report.DevHoursPerDay[user][day] += float32(timelog.TimeSpent) / 3600
+			}
+			report.TotalHours[user] += float32(timelog.TimeSpent) / 3600
+			week := isoWeek(spentAt, local)
+			if report.HoursPerWeek[week] == nil {
+				report.HoursPerWeek[week] = make(map[string]float32)
+			}
+			report.HoursPerWeek[week][user] += float32(timelog.TimeSpent) / 3600
+			report.GrandTotalSeconds += timelog.TimeSpent
+			if timelog.TimeSpent < 0 {
+				report.CorrectionsTotal += float32(timelog.TimeSpent) / 3600
+			}
+			issueKey := issue.ProjectPath + referencePrefix(issue.MergeRequest) + issue.IID
+			if !seenIssues[issueKey] {
+				seenIssues[issueKey] = true
+				report.TotalIssues++
+			}
+			if !seenUserIssues[user+"\x00"+issueKey] {
+				seenUserIssues[user+"\x00"+issueKey] = true
+				report.IssuesPerUser[user]++
+			}
+			report.Entries = append(report.Entries, newDetailEntry(issue, timelog, spentAt, reportOptions))
+		}
+	}
+
+	// Requested users without logs are reported with 0h, keyed by username since their id is unknown
+	loggedUsernames := make(map[string]bool)
+	for _, username := range report.Usernames {
+		loggedUsernames[username] = true
+	}
+	for _, username := range reportOptions.Usernames {
+		if !loggedUsernames[username] {
+			report.Usernames[username] = username
+			report.DevHours[username] = 0
+			report.NonDevHours[username] = 0
+			report.TotalHours[username] = 0
+		}
+	}
+
+	return report
+}
+
+func (report AllUsersReport) userLabel(user string, keyBy string) string {
+	// users without logs are keyed by username
+	if keyBy == "id" && user != report.Usernames[user] {
+		return fmt.Sprintf("%s (id %s)", report.Usernames[user], user)
+	}
+	return user
+}
+
+// Map order is random, users are printed alphabetically by username or by descending hours of the section
+func (report AllUsersReport) sortedUsers(hoursPerUser map[string]float32, sortBy string) []string {
+	users := make([]string, 0, len(hoursPerUser))
+	for user := range hoursPerUser {
+		users = append(users, user)
+	}
+	sort.SliceStable(users, func(i, j int) bool {
+		if sortBy == "hours" && hoursPerUser[users[i]] != hoursPerUser[users[j]] {
+			return hoursPerUser[users[i]] > hoursPerUser[users[j]]
+		}
+		if report.Usernames[users[i]] != report.Usernames[users[j]] {
+			return report.Usernames[users[i]] < report.Usernames[users[j]]
+		}
+		return users[i] < users[j]
+	})
+	return users
+}
+
+// JSON document of the all users report, printed by OUTPUT_FORMAT=json and served by SERVE_ADDR
+func allUsersSpentTimeReport(report AllUsersReport, reportOptions ReportOptions) SpentTimeReport {
+	entries := report.Entries
+	if reportOptions.CollapseEntries {
+		entries = collapseEntries(entries)
+	}
+	jsonReport := SpentTimeReport{
+		StartDate:        report.Start.Format("2006-01-02"),
+		EndDate:          report.End.Format("2006-01-02"),
+		RunTag:           reportOptions.RunTag,
+		Entries:          entries,
+		NonDevCategories: make(map[string]float32),
+		CorrectionsTotal: roundHours(report.CorrectionsTotal, reportOptions.RoundingMode),
+	}
+	for category, time := range report.NonDevHoursPerCategory {
+		jsonReport.NonDevCategories[category] = roundHours(time, reportOptions.RoundingMode)
+	}
+	for _, user := range report.sortedUsers(report.TotalHours, reportOptions.SortBy) {
+		devHours := roundHours(report.DevHours[user], reportOptions.RoundingMode)
+		nonDevHours := roundHours(report.NonDevHours[user], reportOptions.RoundingMode)
+		jsonReport.Users = append(jsonReport.Users, UserSpentTime{
+			Username:    report.Usernames[user],
+			UserID:      report.UserIDs[user],
+			Hours:       roundHours(report.TotalHours[user], reportOptions.RoundingMode),
+			DevHours:    &devHours,
+			NonDevHours: &nonDevHours,
+		})
+	}
+
+	return jsonReport
+}
+
+func printAllUsersReport(report AllUsersReport, reportOptions ReportOptions, stats *RunStats) error {
+	if reportOptions.JSONOutput {
+		return writeSpentTimeReport(reportOptions.Output, allUsersSpentTimeReport(report, reportOptions))
+	}
+
+	logDetails(report.Entries, reportOptions, true, stats)
+	if reportOptions.ShowUpdatedAt {
+		logStaleIssues(report.Entries, report.Start, reportOptions)
+	}
+
+	// With TopN only the users who logged the most are printed, the others are summed into one line
+	logUsers := func(hoursPerUser map[string]float32, hoursPerDay map[string]map[string]float32, color string) float32 {
+		users := report.sortedUsers(hoursPerUser, reportOptions.SortBy)
+		if reportOptions.TopN > 0 {
+			sort.SliceStable(users, func(i, j int) bool {
+				return hoursPerUser[users[i]] > hoursPerUser[users[j]]
+			})
+		}
+
+		var totalSpentTime, otherTime float32
+		for i, user := range users {
+			totalSpentTime += hoursPerUser[user]
+			if reportOptions.TopN > 0 && i >= reportOptions.TopN {
+				otherTime += hoursPerUser[user]
+				continue
+			}
+			log.Printf(colorize(translate(reportOptions.Language, "from %s to %s for %s : %.1fh"), color)+"%s", report.Start.Format("2006-01-02"), report.End.Format("2006-01-02"), report.userLabel(user, reportOptions.KeyBy), roundHours(hoursPerUser[user], reportOptions.RoundingMode), sparklineSuffix(report.Start, report.End, hoursPerDay[user], reportOptions))
+		}
+		if reportOptions.TopN > 0 && len(users) > reportOptions.TopN {
+			log.Printf(colorize(translate(reportOptions.Language, "others (%d users) : %.1fh"), color), len(users)-reportOptions.TopN, roundHours(otherTime, reportOptions.RoundingMode))
+		}
+		return totalSpentTime
+	}
+
+	log.Println(sectionHeader(translate(reportOptions.Language, "-- Total dev time spent --")))
+	totalDevSpentTime := logUsers(report.DevHours, report.DevHoursPerDay, "")
+
+	log.Printf(translate(reportOptions.Language, "Total : %.1fh"), roundHours(totalDevSpentTime, reportOptions.RoundingMode))
+
+	log.Println(sectionHeader(translate(reportOptions.Language, "-- Total NON dev time spent--")))
+	totalNonDevSpentTime := logUsers(report.NonDevHours, report.NonDevHoursPerDay, ansiCyan)
+
+	log.Printf(colorize(translate(reportOptions.Language, "Total : %.1fh"), ansiCyan), roundHours(totalNonDevSpentTime, reportOptions.RoundingMode))
+
+	// A single category is the non dev total itself
+	if len(report.NonDevHoursPerCategory) > 1 {
+		log.Println(sectionHeader(translate(reportOptions.Language, "-- NON dev time per category --")))
+		categories := make([]string, 0, len(report.NonDevHoursPerCategory))
+		for category := range report.NonDevHoursPerCategory {
+			categories = append(categories, category)
+		}
+		sort.Strings(categories)
+		for _, category := range categories {
+			log.Printf("%s : %.1fh", category, roundHours(report.NonDevHoursPerCategory[category], reportOptions.RoundingMode))
+		}
+	}
+
+	// Users without any time, or only corrections, have no share rather than a division by zero
+	logNonDevShare := func(label string, nonDevHours float32, hours float32) {
+		if hours <= 0 {
+			log.Printf("%s : -", label)
+			return
+		}
+		log.Printf("%s : %.0f%%", label, nonDevHours/hours*100)
+	}
+	log.Println(sectionHeader(translate(reportOptions.Language, "-- NON dev share of the time spent --")))
+	for _, user := range report.sortedUsers(report.TotalHours, reportOptions.SortBy) {
+		logNonDevShare(report.userLabel(user, reportOptions.KeyBy), report.NonDevHours[user], report.DevHours[user]+report.NonDevHours[user])
+	}
+	logNonDevShare("Total", totalNonDevSpentTime, totalDevSpentTime+totalNonDevSpentTime)
+
+	if report.CorrectionsTotal != 0 {
+		log.Printf(translate(reportOptions.Language, "Corrections : %.1fh"), roundHours(report.CorrectionsTotal, reportOptions.RoundingMode))
+	}
+
+	log.Println(sectionHeader(translate(reportOptions.Language, "-- Issues per user --")))
+	for _, user := range report.sortedUsers(report.TotalHours, reportOptions.SortBy) {
+		logIssueCount(report.userLabel(user, reportOptions.KeyBy), report.IssuesPerUser[user], report.TotalHours[user], reportOptions)
+	}
+	logIssueCount("Total", report.TotalIssues, totalDevSpentTime+totalNonDevSpentTime, reportOptions)
+
+	if reportOptions.VerifyTotals {
+		grandTotal := float32(report.GrandTotalSeconds) / 3600
+		if diff := grandTotal - (totalDevSpentTime + totalNonDevSpentTime); diff > 0.01 || diff < -0.01 {
+			logger.Warn("Dev + non dev totals differ from the grand total", "devAndNonDevHours", totalDevSpentTime+totalNonDevSpentTime, "grandTotalHours", grandTotal)
+		}
+	}
+
+	if reportOptions.Breakdown == "weekly" {
+		logWeeklyMatrix(report, reportOptions)
+	}
+
+	if reportOptions.Capacities != nil {
+		log.Println(sectionHeader(translate(reportOptions.Language, "-- Capacity --")))
+		for _, user := range report.sortedUsers(report.TotalHours, reportOptions.SortBy) {
+			logCapacity(reportOptions.Capacities, report.Usernames[user], report.TotalHours[user], report.TimeOffHours[user])
+		}
+	}
+
+	return nil
+}
+
+// One row per ISO week and one column per user, the last column and row are totals
+func logWeeklyMatrix(report AllUsersReport, reportOptions ReportOptions) {
+	hours := func(h float32) string {
+		return fmt.Sprintf("%.1fh", roundHours(h, reportOptions.RoundingMode))
+	}
+	users := report.sortedUsers(report.TotalHours, reportOptions.SortBy)
+
+	log.Println(sectionHeader(translate(reportOptions.Language, "-- Time per week --")))
+	header := []string{"Week"}
+	for _, user := range users {
+		header = append(header, report.userLabel(user, reportOptions.KeyBy))
+	}
+	log.Println(strings.Join(append(header, "Total"), " | "))
+	weeks := make([]string, 0, len(report.HoursPerWeek))
+	for week := range report.HoursPerWeek {
+		weeks = append(weeks, week)
+	}
+	sort.Strings(weeks)
+	var total float32
+	for _, week := range weeks {
+		row := []string{week}
+		var weekTotal float32
+		for _, user := range users {
+			row = append(row, hours(report.HoursPerWeek[week][user]))
+			weekTotal += report.HoursPerWeek[week][user]
+		}
+		total += weekTotal
+		log.Println(strings.Join(append(row, hours(weekTotal)), " | "))
+	}
+	row := []string{"Total"}
+	for _, user := range users {
+		row = append(row, hours(report.TotalHours[user]))
+	}
+	log.Println(strings.Join(append(row, hours(total)), " | "))
+}
+
+// Number of distinct issues and the average hours spent on each of them
+func logIssueCount(label string, issues int, hours float32, reportOptions ReportOptions) {
+	if issues == 0 {
+		log.Printf("%s : 0 issues", label)
+		return
+	}
+	log.Printf("%s : %d issues, %.1fh per issue", label, issues, roundHours(hours/float32(issues), reportOptions.RoundingMode))
+}
+
+// Sum spent time per epic title, for a single user or for everyone when username is empty
+func getEpicSpentTime(start time.Time, end time.Time, username string, timelogData *TimelogData, reportOptions ReportOptions, stats *RunStats) {
+	getSpentTimePerGroup(start, end, username, timelogData, reportOptions, stats, "-- Total time spent per epic --", "no epic", func(issue Issue) (string, bool) {
+		if issue.Epic == nil {
+			return "", false
+		}
+		return issue.Epic.Title, true
+	})
+}
+
+// Sum spent time per milestone title, to compare sprints
+func getMilestoneSpentTime(start time.Time, end time.Time, username string, timelogData *TimelogData, reportOptions ReportOptions, stats *RunStats) {
+	getSpentTimePerGroup(start, end, username, timelogData, reportOptions, stats, "-- Total time spent per milestone --", "(unassigned)", func(issue Issue) (string, bool) {
+		if issue.Milestone == nil {
+			return "", false
+		}
+		return issue.Milestone.Title, true
+	})
+}
+
+// Sum spent time per iteration, to compare sprints of teams planning with iterations rather than milestones
+func getIterationSpentTime(start time.Time, end time.Time, username string, timelogData *TimelogData, reportOptions ReportOptions, stats *RunStats) {
+	getSpentTimePerGroup(start, end, username, timelogData, reportOptions, stats, "-- Total time spent per iteration --", "(no iteration)", func(issue Issue) (string, bool) {
+		if issue.Iteration == nil {
+			return "", false
+		}
+		return issue.Iteration.label(), true
+	})
+}
+
+// Sum spent time per group title of the issues, groups are printed alphabetically and issues without one last under noGroup
+func getSpentTimePerGroup(start time.Time, end time.Time, username string, timelogData *TimelogData, reportOptions ReportOptions, stats *RunStats, header string, noGroup string, groupOf func(Issue) (string, bool)) {
+	totalTimePerGroup := make(map[string]float32)
+
+	local := reportOptions.FilterLocation
+	date := start.Format("2006-01-02")
+	endDate := end.Format("2006-01-02")
+
+	stats.IssuesFetched += len(timelogData.Project.Issues.Nodes)
+	for _, issue := range timelogData.Project.Issues.Nodes {
+		stats.IssuesFiltered++
+
+		group, ok := groupOf(issue)
+		if !ok {
+			group = noGroup
+		}
+
+		for _, timelog := range issue.Timelogs.Nodes {
+			stats.TimelogsScanned++
+
+			if _, _, ok := withinWindow(issue, timelog, start, end, local, stats); !ok {
+				continue
+			}
+			if username != "" && timelog.User.Username != username {
+				stats.Excluded["username"]++
+				continue
+			}
+
+			stats.TimelogsIncluded++
+			totalTimePerGroup[group] += float32(timelog.TimeSpent) / 3600
+		}
+	}
+
+	groups := make([]string, 0, len(totalTimePerGroup))
+	for group := range totalTimePerGroup {
+		if group != noGroup {
+			groups = append(groups, group)
+		}
+	}
+	sort.Strings(groups)
+	if _, ok := totalTimePerGroup[noGroup]; ok {
+		groups = append(groups, noGroup)
+	}
+
+	log.Println(sectionHeader(translate(reportOptions.Language, header)))
+	var totalSpentTime float32
+	for _, group := range groups {
+		log.Printf(translate(reportOptions.Language, "from %s to %s for %s : %.1fh"), date, endDate, group, roundHours(totalTimePerGroup[group], reportOptions.RoundingMode))
+		totalSpentTime += totalTimePerGroup[group]
+	}
+	log.Printf(translate(reportOptions.Language, "Total : %.1fh"), roundHours(totalSpentTime, reportOptions.RoundingMode))
+}
+
+// Share of a total as a percentage, n/a when corrections net the total to zero
+func percentOf(hours float32, total float32) string {
+	if isZeroHours(total) {
+		return "n/a"
+	}
+
+	return fmt.Sprintf("%.1f%%", hours/total*100)
+}
+
+// Issue of the GROUP_BY=issue JSON document, keyed by its reference, with the hours of each user on it
+type IssueSpentTime struct {
+	Title string             `json:"title"`
+	Total float32            `json:"total"`
+	Users map[string]float32 `json:"users"`
+}
+
+// Print each issue's share of the total spent time, biggest first, with a cumulative share, or the issues with their per user hours as a JSON object
+func getIssueSpentTime(start time.Time, end time.Time, username string, timelogData *TimelogData, reportOptions ReportOptions, asJSON bool, stats *RunStats) error {
+	type issueTime struct {
+		ProjectPath  string
+		MergeRequest bool
+		IID          string
+		Title        string
+		Hours        float32
+		// username = hours
+		UserHours map[string]float32
+	}
+
+	var issues []*issueTime
+	issueIndex := make(map[string]*issueTime)
+	var totalSpentTime float32
+	for _, entry := range getTimesheetEntries(start, end, username, timelogData, reportOptions, stats) {
+		key := entry.ProjectPath + referencePrefix(entry.MergeRequest) + entry.IID
+		if _, ok := issueIndex[key]; !ok {
+			issueIndex[key] = &issueTime{ProjectPath: entry.ProjectPath, MergeRequest: entry.MergeRequest, IID: entry.IID, Title: entry.Title, UserHours: make(map[string]float32)}
+			issues = append(issues, issueIndex[key])
+		}
+		issueIndex[key].Hours += entry.Hours
+		issueIndex[key].UserHours[entry.Username] += entry.Hours
+		totalSpentTime += entry.Hours
+	}
+
+	if asJSON {
+		jsonIssues := make(map[string]IssueSpentTime)
+		for _, issue := range issues {
+			if isZeroHours(issue.Hours) && !reportOptions.ShowZero {
+				continue
+			}
+			jsonIssue := IssueSpentTime{Title: issue.Title, Total: roundHours(issue.Hours, reportOptions.RoundingMode), Users: make(map[string]float32)}
+			for user, hours := range issue.UserHours {
+				jsonIssue.Users[user] = roundHours(hours, reportOptions.RoundingMode)
+			}
+			jsonIssues[issueRef(issue.ProjectPath, issue.IID, issue.MergeRequest, reportOptions)] = jsonIssue
+		}
+		return json.NewEncoder(reportOptions.Output).Encode(jsonIssues)
+	}
+
+	sort.SliceStable(issues, func(i, j int) bool {
+		return issues[i].Hours > issues[j].Hours
+	})
+
+	log.Println(sectionHeader(translate(reportOptions.Language, "-- Time per issue --")))
+	var cumulativeTime, otherTime float32
+	var otherIssues int
+	for _, issue := range issues {
+		cumulativeTime += issue.Hours
+		// an issue whose time was entirely corrected has nothing to show
+		if isZeroHours(issue.Hours) && !reportOptions.ShowZero {
+			continue
+		}
+		if reportOptions.IssueDetailMinHours > 0 && issue.Hours <= reportOptions.IssueDetailMinHours {
+			otherTime += issue.Hours
+			otherIssues++
+			continue
+		}
+		log.Printf("%.1fh (%s, cumulative %s) - %s: %s%s", roundHours(issue.Hours, reportOptions.RoundingMode), percentOf(issue.Hours, totalSpentTime), percentOf(cumulativeTime, totalSpentTime), issueRef(issue.ProjectPath, issue.IID, issue.MergeRequest, reportOptions), issue.Title, issueLink(issue.ProjectPath, issue.IID, issue.MergeRequest, reportOptions))
+	}
+	if otherIssues > 0 {
+		log.Printf(translate(reportOptions.Language, "other issues: %.1fh (%d issues)"), roundHours(otherTime, reportOptions.RoundingMode), otherIssues)
+	}
+	log.Printf(translate(reportOptions.Language, "Total : %.1fh"), roundHours(totalSpentTime, reportOptions.RoundingMode))
+
+	return nil
+}
+
+// Hours logged per weight point on closed issues, issues without weight are left out of the ratio
+func getVelocity(start time.Time, end time.Time, username string, timelogData *TimelogData, reportOptions ReportOptions, stats *RunStats) {
+	closedWeights := make(map[string]int)
+	for _, issue := range timelogData.Project.Issues.Nodes {
+		if issue.State == "closed" && issue.Weight != nil && *issue.Weight > 0 {
+			closedWeights[issue.ProjectPath+referencePrefix(issue.MergeRequest)+issue.IID] = *issue.Weight
+		}
+	}
+
+	var closedTime float32
+	countedIssues := make(map[string]bool)
+	for _, entry := range getTimesheetEntries(start, end, username, timelogData, reportOptions, stats) {
+		key := entry.ProjectPath + referencePrefix(entry.MergeRequest) + entry.IID
+		if _, ok := closedWeights[key]; ok {
+			closedTime += entry.Hours
+			countedIssues[key] = true
+		}
+	}
+
+	var totalWeight int
+	for key := range countedIssues {
+		totalWeight += closedWeights[key]
+	}
+
+	if totalWeight == 0 {
+		log.Println("Velocity : no closed issue with a weight and logged time")
+		return
+	}
+	log.Printf("Velocity : %.1fh for %d weight points on %d closed issues, %.2fh per point", roundHours(closedTime, reportOptions.RoundingMode), totalWeight, len(countedIssues), closedTime/float32(totalWeight))
+}
+
+// List open issues with an estimate but little or no time logged on them yet
+func getAgingReport(threshold float64, timelogData *TimelogData, reportOptions ReportOptions) {
+	var agingIssues []Issue
+	for _, issue := range timelogData.Project.Issues.Nodes {
+		if !issue.MergeRequest && issue.State == "opened" && issue.TimeEstimate > 0 && float64(issue.TotalTimeSpent)/float64(issue.TimeEstimate) < threshold {
+			agingIssues = append(agingIssues, issue)
+		}
+	}
+
+	sort.SliceStable(agingIssues, func(i, j int) bool {
+		return float64(agingIssues[i].TotalTimeSpent)/float64(agingIssues[i].TimeEstimate) < float64(agingIssues[j].TotalTimeSpent)/float64(agingIssues[j].TimeEstimate)
+	})
+
+	log.Printf(sectionHeader(translate(reportOptions.Language, "-- Open issues with less than %.0f%% of their estimate logged --")), threshold*100)
+	for _, issue := range agingIssues {
+		log.Printf("%.1fh / %.1fh estimated (%.0f%%) - %s: %s%s", float32(issue.TotalTimeSpent)/3600, float32(issue.TimeEstimate)/3600, float64(issue.TotalTimeSpent)/float64(issue.TimeEstimate)*100, issueRef(issue.ProjectPath, issue.IID, false, reportOptions), issue.Title, issueLink(issue.ProjectPath, issue.IID, false, reportOptions))
+	}
+	log.Printf("Total : %d issues", len(agingIssues))
+}
+
+// Compare the whole logged time of issues worked on within the window with their estimate, issues without one are listed apart
+func getEstimateReport(start time.Time, end time.Time, username string, timelogData *TimelogData, reportOptions ReportOptions, stats *RunStats) {
+	workedOn := make(map[string]bool)
+	for _, entry := range getTimesheetEntries(start, end, username, timelogData, reportOptions, stats) {
+		workedOn[entry.ProjectPath+referencePrefix(entry.MergeRequest)+entry.IID] = true
+	}
+
+	var estimatedIssues, unestimatedIssues []Issue
+	for _, issue := range timelogData.Project.Issues.Nodes {
+		if issue.MergeRequest || !workedOn[issue.ProjectPath+"#"+issue.IID] {
+			continue
+		}
+		if issue.TimeEstimate > 0 {
+			estimatedIssues = append(estimatedIssues, issue)
+		} else {
+			unestimatedIssues = append(unestimatedIssues, issue)
+		}
+	}
+
+	// most over estimate first
+	sort.SliceStable(estimatedIssues, func(i, j int) bool {
+		return estimatedIssues[i].TotalTimeSpent-estimatedIssues[i].TimeEstimate > estimatedIssues[j].TotalTimeSpent-estimatedIssues[j].TimeEstimate
+	})
+
+	log.Println(sectionHeader(translate(reportOptions.Language, "-- Spent time vs estimate --")))
+	var totalSpentTime, totalEstimate float32
+	for _, issue := range estimatedIssues {
+		spentTime := float32(issue.TotalTimeSpent) / 3600
+		estimate := float32(issue.TimeEstimate) / 3600
+		log.Printf("%.1fh / %.1fh estimated (%+.1fh) - %s: %s%s", roundHours(spentTime, reportOptions.RoundingMode), roundHours(estimate, reportOptions.RoundingMode), roundHours(spentTime-estimate, reportOptions.RoundingMode), issueRef(issue.ProjectPath, issue.IID, false, reportOptions), issue.Title, issueLink(issue.ProjectPath, issue.IID, false, reportOptions))
+		totalSpentTime += spentTime
+		totalEstimate += estimate
+	}
+	log.Printf("Total : %.1fh / %.1fh estimated (%+.1fh)", roundHours(totalSpentTime, reportOptions.RoundingMode), roundHours(totalEstimate, reportOptions.RoundingMode), roundHours(totalSpentTime-totalEstimate, reportOptions.RoundingMode))
+
+	log.Println(sectionHeader(translate(reportOptions.Language, "-- Issues without estimate --")))
+	var unestimatedSpentTime float32
+	for _, issue := range unestimatedIssues {
+		spentTime := float32(issue.TotalTimeSpent) / 3600
+		log.Printf("%.1fh - %s: %s%s", roundHours(spentTime, reportOptions.RoundingMode), issueRef(issue.ProjectPath, issue.IID, false, reportOptions), issue.Title, issueLink(issue.ProjectPath, issue.IID, false, reportOptions))
+		unestimatedSpentTime += spentTime
+	}
+	log.Printf("Total : %.1fh on %d issues", roundHours(unestimatedSpentTime, reportOptions.RoundingMode), len(unestimatedIssues))
+}
+
+// Value at the given percentile using the nearest-rank method, values must be sorted
+func percentile(sortedValues []float32, p float64) float32 {
+	rank := int(math.Ceil(p / 100 * float64(len(sortedValues))))
+	if rank < 1 {
+		rank = 1
+	}
+
+	return sortedValues[rank-1]
+}
+
+// Median and 90th percentile of the hours each user logged per day, days without logs are left out
+func getDailyStats(start time.Time, end time.Time, username string, timelogData *TimelogData, reportOptions ReportOptions, stats *RunStats) {
+	// username = day = hours
+	dailyTimePerUser := make(map[string]map[string]float32)
+	for _, entry := range getTimesheetEntries(start, end, username, timelogData, reportOptions, stats) {
+		if dailyTimePerUser[entry.Username] == nil {
+			dailyTimePerUser[entry.Username] = make(map[string]float32)
+		}
+		dailyTimePerUser[entry.Username][entry.SpentAt.In(reportOptions.FilterLocation).Format("2006-01-02")] += entry.Hours
+	}
+
+	usernames := make([]string, 0, len(dailyTimePerUser))
+	for username := range dailyTimePerUser {
+		usernames = append(usernames, username)
+	}
+	sort.Strings(usernames)
+
+	log.Println(sectionHeader(translate(reportOptions.Language, "-- Daily hours stats --")))
+	for _, username := range usernames {
+		var dailyTimes []float32
+		for _, time := range dailyTimePerUser[username] {
+			dailyTimes = append(dailyTimes, time)
+		}
+		sort.Slice(dailyTimes, func(i, j int) bool { return dailyTimes[i] < dailyTimes[j] })
+
+		median := dailyTimes[len(dailyTimes)/2]
+		if len(dailyTimes)%2 == 0 {
+			median = (dailyTimes[len(dailyTimes)/2-1] + dailyTimes[len(dailyTimes)/2]) / 2
+		}
+		log.Printf("%s : median %.1fh, p90 %.1fh over %d days", username, roundHours(median, reportOptions.RoundingMode), roundHours(percentile(dailyTimes, 90), reportOptions.RoundingMode), len(dailyTimes))
+	}
+}
+
+// Timelogs only carry their creation date through the note of the /spend quick action, spentAt is used otherwise
+func timelogRecordedAt(timelog Timelog) (time.Time, error) {
+	if timelog.Note != nil && timelog.Note.CreatedAt != "" {
+		return time.Parse(time.RFC3339, timelog.Note.CreatedAt)
+	}
+
+	return time.Parse(time.RFC3339, timelog.SpentAt)
+}
+
+// Print the timelogs recorded after a timestamp, whatever their spent date, for a single user or for everyone when username is empty
+func getSpentTimeSince(since time.Time, username string, timelogData *TimelogData, reportOptions ReportOptions, stats *RunStats) {
+	var totalSpentTime float32
+	var entries []DetailEntry
+
+	for _, issue := range timelogData.Project.Issues.Nodes {
+		for _, timelog := range issue.Timelogs.Nodes {
+			stats.TimelogsScanned++
+
+			recordedAt, err := timelogRecordedAt(timelog)
+			if err != nil || !recordedAt.After(since) {
+				stats.Excluded["since timestamp"]++
+				continue
+			}
+			if username != "" && timelog.User.Username != username {
+				stats.Excluded["username"]++
+				continue
+			}
+
+			spentAt, ok := parseSpentAt(issue, timelog, stats)
+			if !ok {
+				continue
+			}
+			stats.TimelogsIncluded++
+			totalSpentTime += float32(timelog.TimeSpent) / 3600
+			entries = append(entries, newDetailEntry(issue, timelog, spentAt, reportOptions))
+		}
+	}
+
+	logDetails(entries, reportOptions, username == "", stats)
+	log.Printf("Total added since %s : %.1fh", since.Format(time.RFC3339), roundHours(totalSpentTime, reportOptions.RoundingMode))
+}
+
+// Render hours as unicode blocks scaled to the biggest value, zero is the lowest block
+func sparkline(values []float32) string {
+	blocks := []rune("▁▂▃▄▅▆▇█")
+
+	var max float32
+	for _, value := range values {
+		if value > max {
+			max = value
+		}
+	}
+
+	line := make([]rune, len(values))
+	for i, value := range values {
+		level := 0
+		if max > 0 && value > 0 {
+			level = int(value / max * float32(len(blocks)-1))
+		}
+		line[i] = blocks[level]
+	}
+
+	return string(line)
+}
+
+// Sparkline of the daily hours of the window appended to a total line, scaled to its own busiest day
+func sparklineSuffix(start time.Time, end time.Time, hoursPerDay map[string]float32, reportOptions ReportOptions) string {
+	if !reportOptions.Sparkline {
+		return ""
+	}
+
+	var dailyTimes []float32
+	for day := start; !day.After(end); day = day.AddDate(0, 0, 1) {
+		dailyTimes = append(dailyTimes, hoursPerDay[day.Format("2006-01-02")])
+	}
+
+	return " " + sparkline(dailyTimes)
+}
+
+// Team hours of one day of the window
+type DateSpentTime struct {
+	Date         string  `json:"date"`
+	Hours        float32 `json:"hours"`
+	Contributors int     `json:"contributors"`
+	// the JSON array has no document around it, RUN_TAG is repeated on every day
+	RunTag string `json:"run_tag,omitempty"`
+}
+
+// Hours logged by the whole team on each day of the window, as a text table or a JSON array on stdout
+func getDateSpentTime(start time.Time, end time.Time, timelogData *TimelogData, reportOptions ReportOptions, asJSON bool, stats *RunStats) error {
+	var dates []DateSpentTime
+	dateIndex := make(map[string]int)
+	for day := start; !day.After(end); day = day.AddDate(0, 0, 1) {
+		dateIndex[day.Format("2006-01-02")] = len(dates)
+		dates = append(dates, DateSpentTime{Date: day.Format("2006-01-02"), RunTag: reportOptions.RunTag})
+	}
+
+	// date = username = true
+	contributors := make(map[string]map[string]bool)
+	for _, entry := range getTimesheetEntries(start, end, "", timelogData, reportOptions, stats) {
+		date := entry.SpentAt.In(reportOptions.FilterLocation).Format("2006-01-02")
+		day, ok := dateIndex[date]
+		if !ok {
+			continue
+		}
+
+		dates[day].Hours += entry.Hours
+		if contributors[date] == nil {
+			contributors[date] = make(map[string]bool)
+		}
+		contributors[date][entry.Username] = true
+		dates[day].Contributors = len(contributors[date])
+	}
+
+	if asJSON {
+		return json.NewEncoder(reportOptions.Output).Encode(dates)
+	}
+
+	log.Println(sectionHeader(translate(reportOptions.Language, "-- Team time per date --")))
+	var totalSpentTime float32
+	for _, date := range dates {
+		log.Printf("%s : %.1fh by %d contributors", date.Date, roundHours(date.Hours, reportOptions.RoundingMode), date.Contributors)
+		totalSpentTime += date.Hours
+	}
+	log.Printf(translate(reportOptions.Language, "Total : %.1fh"), roundHours(totalSpentTime, reportOptions.RoundingMode))
+
+	return nil
+}
+
+func main() {
+	if err := run(); err != nil {
+		logger.Error(err.Error())
+		os.Exit(exitCode(err))
+	}
+}
+
+func run() error {
+	if err := applyFlags(os.Args[1:]); err != nil {
+		return &exitError{code: exitConfig, err: err}
+	}
+
+	// godotenv never overrides a variable already set, so files are loaded last first for later files to win
+	envFiles := []string{".env"}
+	if envFilesEnv := os.Getenv("ENV_FILES"); envFilesEnv != "" {
+		envFiles = strings.Split(envFilesEnv, ",")
+	}
+	for i := len(envFiles) - 1; i >= 0; i-- {
+		if err := godotenv.Load(strings.TrimSpace(envFiles[i])); err != nil {
+			logger.Warn("Could not load env file", "file", envFiles[i], "error", err)
+		}
+	}
+	// read last so flags, env vars and env files all win over it
+	config, err := loadConfig(os.LookupEnv, os.Getenv("CONFIG_FILE"))
+	if err != nil {
+		return exitErrorf(exitConfig, "Failed to load CONFIG_FILE: %v", err)
+	}
+
+	return runWithConfig(config)
+}
+
+// Keeps the first write error, the log package drops the errors of the report lines it writes
+type checkedWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (c *checkedWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	if err != nil && c.err == nil {
+		c.err = err
+	}
+
+	return n, err
+}
+
+// Everything after the options are read, they all come from config and none from the env
+// A failed write or close of OUTPUT_FILE is returned once the run is over, the text reports after the formats go to it too
+func runWithConfig(config Config) (runErr error) {
+	if err := validateConfig(config.get); err != nil {
+		return &exitError{code: exitConfig, err: err}
+	}
+
+	var err error
+
+	// Check env vars
+	// OAuth2 application tokens expire hourly and are refreshed as needed, GITLAB_TOKEN is used when they are not configured
+	oauthClientID := config.OAuthClientID
+	oauthClientSecret := config.OAuthClientSecret
+	oauthRefreshTokenFile := config.OAuthRefreshTokenFile
+	useOAuth := oauthClientID != "" || oauthClientSecret != "" || config.OAuthRefreshToken != "" || oauthRefreshTokenFile != ""
+	var oauthRefreshToken string
+	apiToken := config.GitlabToken
+	if useOAuth {
+		oauthRefreshToken, err = readRefreshToken(config.OAuthRefreshToken, oauthRefreshTokenFile)
+		if err != nil {
+			return exitErrorf(exitConfig, "Failed to read OAUTH_REFRESH_TOKEN_FILE: %v", err)
+		}
+		if oauthClientID == "" || oauthClientSecret == "" || oauthRefreshToken == "" {
+			return exitErrorf(exitConfig, "OAUTH_CLIENT_ID, OAUTH_CLIENT_SECRET and OAUTH_REFRESH_TOKEN or OAUTH_REFRESH_TOKEN_FILE must all be set to use OAuth2")
+		}
+		// the access tokens are set by the OAuth2 transport, the clients send none of their own
+		apiToken = ""
+	} else if apiToken == "" {
+		return exitErrorf(exitConfig, "GITLAB_TOKEN environment variable is not set")
+	}
+	webhookAuthName, webhookAuthValue, err := parseWebhookAuthHeader(config.WebhookAuthHeader)
+	if err != nil {
+		return &exitError{code: exitConfig, err: err}
+	}
+	secrets := []string{apiToken, oauthClientSecret, oauthRefreshToken, webhookAuthValue}
+
+	// The diagnostics are written to stderr and the report to stdout, make sure the token can never leak from either
+	diagnostics := &redactingWriter{w: os.Stderr, secrets: secrets}
+	log.SetOutput(diagnostics)
+	// the log package only writes report lines, the date and time of the run are no part of them
+	log.SetFlags(0)
+	logger, err = newLogger(diagnostics, config.LogLevel, config.LogFormat)
+	if err != nil {
+		return &exitError{code: exitConfig, err: err}
+	}
+
+	scope := config.Scope
+	if scope != "" && scope != "my-projects" {
+		return exitErrorf(exitConfig, "SCOPE must be my-projects when set")
+	}
+
+	// Several projects can be reported together, comma-separated, or every project of a group
+	projectIds := splitList(config.GitlabProjectPath)
+	groupPath := config.GitlabGroupPath
+	if len(projectIds) == 0 && scope == "" && groupPath == "" {
+		return exitErrorf(exitConfig, "GITLAB_PROJECT_PATH environment variable is not set")
+	}
+	if groupPath != "" && len(projectIds) > 0 {
+		logger.Info("GITLAB_GROUP_PATH is set, GITLAB_PROJECT_PATH is ignored")
+	}
+
+	gitlabHost := config.GitlabHost
+	if gitlabHost == "" {
+		gitlabHost = "https://gitlab.com"
+		logger.Info("GITLAB_HOST is not set, using default", "host", gitlabHost)
+	}
+
+	gitlabHost, err = normalizeGitlabHost(gitlabHost)
+	if err != nil {
+		return exitErrorf(exitConfig, "GITLAB_HOST must be a valid http(s) URL such as https://gitlab.com: %v", err)
+	}
+
+	daysEnv := config.DaysNum
+	if daysEnv == "" {
+		daysEnv = "0"
+		logger.Info("DAYS_NUM is not set, using default", "days", daysEnv)
+	}
+
+	daysNum, err := strconv.Atoi(daysEnv)
+	if err != nil {
+		return exitErrorf(exitConfig, "DAYS_NUM must be in integer, it represents the number of previous days to fetch issues for")
+	}
+
+	// Gitlab returns spentAt in UTC, the local timezone (or TIMEZONE) is used for day boundaries unless DAY_BOUNDARY=utc
+	dayBoundary := config.DayBoundary
+	if dayBoundary != "" && dayBoundary != "local" && dayBoundary != "utc" {
+		return exitErrorf(exitConfig, "DAY_BOUNDARY must be local or utc")
+	}
+	filterLocation := time.Local
+	if timezone := config.Timezone; timezone != "" {
+		filterLocation, err = time.LoadLocation(timezone)
+		if err != nil {
+			return exitErrorf(exitConfig, "TIMEZONE must be an IANA timezone name such as Europe/Paris: %v", err)
+		}
+	}
+	if dayBoundary == "utc" {
+		filterLocation = time.UTC
+	}
+
+	if period := config.Period; period != "" {
+		daysNum, err = periodDaysNum(period, time.Now().In(filterLocation))
+		if err != nil {
+			return exitErrorf(exitConfig, "PERIOD must be one of week-to-date, month-to-date or year-to-date")
+		}
+		logger.Info("Looking into the days of PERIOD", "period", period, "days", daysNum)
+	}
+
+	// DAYS_NUM and PERIOD define a window ending today, START_DATE and END_DATE set explicit inclusive bounds
+	today := localDay(time.Now(), filterLocation)
+	start := today.AddDate(0, 0, -daysNum)
+	end := today
+	if startEnv := config.StartDate; startEnv != "" {
+		start, err = time.ParseInLocation("2006-01-02", startEnv, filterLocation)
+		if err != nil {
+			return exitErrorf(exitConfig, "START_DATE must be a YYYY-MM-DD date")
+		}
+	}
+	if endEnv := config.EndDate; endEnv != "" {
+		end, err = time.ParseInLocation("2006-01-02", endEnv, filterLocation)
+		if err != nil {
+			return exitErrorf(exitConfig, "END_DATE must be a YYYY-MM-DD date")
+		}
+	}
+	if start.After(end) {
+		return exitErrorf(exitConfig, "START_DATE %s is after END_DATE %s", start.Format("2006-01-02"), end.Format("2006-01-02"))
+	}
+
+	retryOnEmpty := 0
+	if retryEnv := config.RetryOnEmpty; retryEnv != "" {
+		retryOnEmpty, err = strconv.Atoi(retryEnv)
+		if err != nil || retryOnEmpty < 0 {
+			return exitErrorf(exitConfig, "RETRY_ON_EMPTY must be a positive integer, it represents the number of extra attempts when no issues are returned")
+		}
+	}
+
+	maxRetries := 3
+	if maxRetriesEnv := config.MaxRetries; maxRetriesEnv != "" {
+		maxRetries, err = strconv.Atoi(maxRetriesEnv)
+		if err != nil || maxRetries < 0 {
+			return exitErrorf(exitConfig, "MAX_RETRIES must be a positive integer, it represents the number of extra attempts of a failing GraphQL request")
+		}
+	}
+
+	var capacities *Capacities
+	capacitiesFile := config.CapacitiesFile
+	defaultCapacityEnv := config.DefaultCapacity
+	if capacitiesFile != "" || defaultCapacityEnv != "" {
+		var defaultCapacity float64
+		if defaultCapacityEnv != "" {
+			defaultCapacity, err = strconv.ParseFloat(defaultCapacityEnv, 32)
+			if err != nil {
+				return exitErrorf(exitConfig, "DEFAULT_CAPACITY must be a number, it represents the expected hours for users missing from CAPACITIES_FILE")
+			}
+		}
+
+		capacities, err = loadCapacities(capacitiesFile, float32(defaultCapacity))
+		if err != nil {
+			return exitErrorf(exitConfig, "Failed to load capacities: %v", err)
+		}
+	}
+
+	var clockSkewThreshold time.Duration
+	if clockSkewEnv := config.ClockSkewThreshold; clockSkewEnv != "" {
+		clockSkewThreshold, err = time.ParseDuration(clockSkewEnv)
+		if err != nil {
+			return exitErrorf(exitConfig, "CLOCK_SKEW_THRESHOLD must be a duration such as 2m")
+		}
+	}
+
+	// Covers every page of every project, not each request
+	var requestTimeout time.Duration
+	if requestTimeoutEnv := config.RequestTimeout; requestTimeoutEnv != "" {
+		requestTimeout, err = time.ParseDuration(requestTimeoutEnv)
+		if err != nil || requestTimeout <= 0 {
+			return exitErrorf(exitConfig, "REQUEST_TIMEOUT must be a positive duration such as 30s")
+		}
+	}
+
+	// SERVE_ADDR serves the reports over HTTP, fetched timelogs are reused for SERVE_CACHE_TTL
+	serveCacheTTL := time.Minute
+	if ttlEnv := config.ServeCacheTTL; ttlEnv != "" {
+		serveCacheTTL, err = time.ParseDuration(ttlEnv)
+		if err != nil || serveCacheTTL < 0 {
+			return exitErrorf(exitConfig, "SERVE_CACHE_TTL must be a duration such as 30s or 5m, 0 to always fetch")
+		}
+	}
+
+	// CACHE_TTL keeps the fetched issues on disk, the next runs within it do not call Gitlab
+	// a dry run skips it, a cached project would be reported rather than print its query
+	var cache *fileCache
+	if ttlEnv := config.CacheTTL; ttlEnv != "" && config.NoCache != "true" && config.DryRun != "true" {
+		cacheTTL, err := time.ParseDuration(ttlEnv)
+		if err != nil || cacheTTL <= 0 {
+			return exitErrorf(exitConfig, "CACHE_TTL must be a positive duration such as 10m or 1h")
+		}
+		cacheDir := config.CacheDir
+		if cacheDir == "" {
+			userCacheDir, err := os.UserCacheDir()
+			if err != nil {
+				return exitErrorf(exitConfig, "CACHE_DIR must be set, there is no user cache directory: %v", err)
+			}
+			cacheDir = filepath.Join(userCacheDir, "gitlab-issues-data")
+		}
+		cache = &fileCache{dir: cacheDir, ttl: cacheTTL, refresh: config.CacheRefresh == "true"}
+	}
+
+	concurrency := 4
+	if concurrencyEnv := config.Concurrency; concurrencyEnv != "" {
+		concurrency, err = strconv.Atoi(concurrencyEnv)
+		if err != nil || concurrency < 1 {
+			return exitErrorf(exitConfig, "CONCURRENCY must be a positive integer, it represents the number of queries run at the same time")
+		}
+	}
+
+	// CHUNK_BY=month fetches the issues updated in each month of the window concurrently, each with its own pagination
+	var chunkBoundaries []time.Time
+	switch config.ChunkBy {
+	case "":
+	case "month":
+		chunkBoundaries = monthBoundaries(start, end)
+	default:
+		return exitErrorf(exitConfig, "CHUNK_BY must be month when set")
+	}
+
+	getAllUsers := config.AllUsers
+	showStats := config.ShowStats == "true"
+	verifyTotals := config.VerifyTotals == "true"
+
+	requireAllUsersLogged := config.RequireAllUsersLogged == "true"
+	membersSource := config.MembersSource
+	if membersSource == "" {
+		membersSource = "roster"
+	}
+	if membersSource != "roster" && membersSource != "usernames" {
+		return exitErrorf(exitConfig, "MEMBERS_SOURCE must be roster or usernames")
+	}
+	if requireAllUsersLogged && membersSource == "usernames" && config.Usernames == "" {
+		return exitErrorf(exitConfig, "USERNAMES must be set when MEMBERS_SOURCE is usernames")
+	}
+
+	// LANG usually holds the system locale (en_US.UTF-8), only the exact supported values switch the report language
+	language := config.Lang
+	if _, ok := translations[language]; !ok {
+		language = "en"
+	}
+
+	var mixedIssues MixedIssues
+	if mixedIssuesFile := config.MixedIssuesFile; mixedIssuesFile != "" {
+		mixedIssues, err = loadMixedIssues(mixedIssuesFile)
+		if err != nil {
+			return exitErrorf(exitConfig, "Failed to load mixed issues: %v", err)
+		}
+	}
+
+	var issueDetailMinHours float64
+	if minHoursEnv := config.IssueDetailMinHours; minHoursEnv != "" {
+		issueDetailMinHours, err = strconv.ParseFloat(minHoursEnv, 32)
+		if err != nil {
+			return exitErrorf(exitConfig, "ISSUE_DETAIL_MIN_HOURS must be a number of hours")
+		}
+	}
+
+	keyBy := config.KeyBy
+	if keyBy == "" {
+		keyBy = "username"
+	}
+	if keyBy != "username" && keyBy != "id" {
+		return exitErrorf(exitConfig, "KEY_BY must be username or id")
+	}
+
+	var cumulative string
+	if config.Cumulative == "true" {
+		cumulative = config.CumulativeBy
+		if cumulative == "" {
+			cumulative = "all"
+		}
+	}
+	if config.CumulativeBy != "" && config.CumulativeBy != "all" && config.CumulativeBy != "user" {
+		return exitErrorf(exitConfig, "CUMULATIVE_BY must be all or user when set")
+	}
+
+	sortBy := config.SortBy
+	if sortBy != "" && sortBy != "username" && sortBy != "hours" {
+		return exitErrorf(exitConfig, "SORT_BY must be username or hours when set")
+	}
+
+	var topN int
+	if topNEnv := config.TopN; topNEnv != "" {
+		topN, err = strconv.Atoi(topNEnv)
+		if err != nil || topN < 0 {
+			return exitErrorf(exitConfig, "TOP_N must be a positive integer, it represents the number of users printed in each section")
+		}
+	}
+
+	colorMode := config.Color
+	if colorMode != "" && colorMode != "auto" && colorMode != "always" && colorMode != "never" {
+		return exitErrorf(exitConfig, "COLOR must be auto, always or never when set")
+	}
+
+	breakdown := config.Breakdown
+	if breakdown != "" && breakdown != "daily" && breakdown != "weekly" {
+		return exitErrorf(exitConfig, "BREAKDOWN must be daily or weekly when set")
+	}
+
+	roundingMode := config.RoundingMode
+	if roundingMode != "" && roundingMode != "half-up" && roundingMode != "bankers" {
+		return exitErrorf(exitConfig, "ROUNDING_MODE must be half-up or bankers when set")
+	}
+
+	outputFormats := splitList(config.OutputFormat)
+	if len(outputFormats) == 0 {
+		outputFormats = []string{"text"}
+	}
+	for i, outputFormat := range outputFormats {
+		if _, ok := outputFormatExtensions[outputFormat]; !ok {
+			return exitErrorf(exitConfig, "OUTPUT_FORMAT must be one of text, json, csv, markdown, harvest or toggl, or a comma-separated list of them")
+		}
+		if slices.Contains(outputFormats[:i], outputFormat) {
+			return exitErrorf(exitConfig, "OUTPUT_FORMAT lists %s twice", outputFormat)
+		}
+	}
+	if len(outputFormats) > 1 && config.OutputFile == "" {
+		return exitErrorf(exitConfig, "OUTPUT_FORMAT lists several formats, OUTPUT_FILE must be set to write each one to its own file")
+	}
+	if len(outputFormats) > 1 && config.Tee == "true" {
+		return exitErrorf(exitConfig, "TEE writes a single format to stdout, OUTPUT_FORMAT lists several")
+	}
+	if config.Tee == "true" && config.OutputFile == "" {
+		return exitErrorf(exitConfig, "TEE needs OUTPUT_FILE, the report is written to stdout alone without it")
+	}
+	timesheetClient := config.TimesheetClient
+
+	// Spreadsheets in some locales split columns on semicolons and expect a decimal comma
+	csvDelimiter := ','
+	if delimiter := config.CSVDelimiter; delimiter != "" {
+		if delimiter == "tab" {
+			delimiter = "\t"
+		}
+		runes := []rune(delimiter)
+		if len(runes) != 1 || runes[0] == '"' || runes[0] == '\r' || runes[0] == '\n' {
+			return exitErrorf(exitConfig, "CSV_DELIMITER must be a single character other than a quote, such as ; or tab")
+		}
+		csvDelimiter = runes[0]
+	}
+	csvDecimalSeparator := config.CSVDecimalSeparator
+	if csvDecimalSeparator != "" && csvDecimalSeparator != "." && csvDecimalSeparator != "," {
+		return exitErrorf(exitConfig, "CSV_DECIMAL_SEPARATOR must be . or , when set")
+	}
+
+	// Dates are filtered with the day boundaries above, OUTPUT_TIMEZONE only changes how they are printed
+	outputLocation := filterLocation
+	if outputTimezone := config.OutputTimezone; outputTimezone != "" {
+		outputLocation, err = time.LoadLocation(outputTimezone)
+		if err != nil {
+			return exitErrorf(exitConfig, "OUTPUT_TIMEZONE must be an IANA timezone name such as Europe/Paris: %v", err)
+		}
+	}
+
+	// Gitlab returns every state without the argument, all is only accepted to say so
+	issueState := config.IssueState
+	if issueState != "" && issueState != "opened" && issueState != "closed" && issueState != "all" {
+		return exitErrorf(exitConfig, "ISSUE_STATE must be opened, closed or all when set")
+	}
+	if issueState == "all" {
+		issueState = ""
+	}
+	groupBy := config.GroupBy
+	if groupBy != "" && groupBy != "epic" && groupBy != "issue" && groupBy != "date" && groupBy != "milestone" && groupBy != "iteration" {
+		return exitErrorf(exitConfig, "GROUP_BY must be epic, milestone, iteration, issue or date when set")
+	}
+	operationName := config.OperationName
+	if operationName == "" {
+		operationName = "TimelogsReport"
+	}
+	if !regexp.MustCompile(`^[_A-Za-z][_0-9A-Za-z]*$`).MatchString(operationName) {
+		return exitErrorf(exitConfig, "OPERATION_NAME must be a valid GraphQL name (letters, digits and underscores)")
+	}
+
+	velocity := config.Velocity == "true"
+
+	var sinceTimestamp time.Time
+	if sinceEnv := config.SinceTimestamp; sinceEnv != "" {
+		sinceTimestamp, err = time.Parse(time.RFC3339, sinceEnv)
+		if err != nil {
+			return exitErrorf(exitConfig, "SINCE_TIMESTAMP must be an RFC3339 timestamp such as 2024-01-15T09:00:00+01:00")
+		}
+	}
+
+	agingReport := config.AgingReport == "true"
+	estimateReport := config.EstimateReport == "true"
+	verifyNotesTime := config.VerifyNotes == "true"
+
+	// COST_REPORT bills the dev hours of each user at the rate of RATES_FILE
+	costReport := config.CostReport == "true"
+	var rates map[string]float64
+	if costReport {
+		ratesFile := config.RatesFile
+		if ratesFile == "" {
+			return exitErrorf(exitConfig, "RATES_FILE must be set with COST_REPORT, it maps usernames to hourly rates")
+		}
+		rates, err = loadRates(ratesFile)
+		if err != nil {
+			return exitErrorf(exitConfig, "Failed to load rates: %v", err)
+		}
+	}
+	// BUDGET_HOURS fails the run once the project dev time exceeds it, after the report is printed
+	var budgetHours, warnThreshold float64
+	if budgetHoursEnv := config.BudgetHours; budgetHoursEnv != "" {
+		budgetHours, err = strconv.ParseFloat(budgetHoursEnv, 64)
+		if err != nil || budgetHours <= 0 {
+			return exitErrorf(exitConfig, "BUDGET_HOURS must be a positive number, it represents the dev hours the project may spend")
+		}
+	}
+	if warnThresholdEnv := config.WarnThreshold; warnThresholdEnv != "" {
+		warnThreshold, err = strconv.ParseFloat(warnThresholdEnv, 64)
+		if err != nil || warnThreshold <= 0 || warnThreshold > 100 {
+			return exitErrorf(exitConfig, "WARN_THRESHOLD must be a percentage between 0 and 100, it represents the share of BUDGET_HOURS to warn at")
+		}
+		if budgetHours == 0 {
+			return exitErrorf(exitConfig, "WARN_THRESHOLD needs BUDGET_HOURS")
+		}
+	}
+	budgetCumulative := config.BudgetCumulative == "true"
+	currency := config.Currency
+	if currency == "" {
+		currency = "$"
+	}
+	agingThreshold := 0.1
+	if agingThresholdEnv := config.AgingThreshold; agingThresholdEnv != "" {
+		agingThreshold, err = strconv.ParseFloat(agingThresholdEnv, 64)
+		if err != nil || agingThreshold <= 0 {
+			return exitErrorf(exitConfig, "AGING_THRESHOLD must be a positive ratio of logged time over estimate, such as 0.1")
+		}
+	}
+
+	labels := splitList(config.Labels)
+	excludeLabels := splitList(config.ExcludeLabels)
+	for _, label := range labels {
+		for _, excludeLabel := range excludeLabels {
+			if label == excludeLabel {
+				return exitErrorf(exitConfig, "Label %q can not be both in LABELS and EXCLUDE_LABELS", label)
+			}
+		}
+	}
+
+	// several milestones get a subtotal each after the report, their titles are fetched for it
+	milestones := splitList(config.GitlabMilestone)
+	milestoneSubtotals := len(milestones) > 1 && groupBy != "milestone"
+
+	// the timesheet imports name their users, the other formats only need the username
+	withTimesheet := slices.ContainsFunc(outputFormats, func(format string) bool {
+		_, isTimesheet := timesheetFormats[format]
+		return isTimesheet
+	})
+
+	queryOptions := QueryOptions{
+		OperationName: operationName,
+		WithEpic:      groupBy == "epic",
+		WithMilestone: groupBy == "milestone" || milestoneSubtotals,
+		WithLabels:    config.TimeOffLabel != "",
+		Milestones:    milestones,
+		State:         issueState,
+		WithIteration: groupBy == "iteration",
+		Iteration:     config.GitlabIteration,
+		Assignee:      config.GitlabAssignee,
+		WithWeight:    velocity,
+		Search:        config.Search,
+		Labels:        labels,
+		ExcludeLabels: excludeLabels,
+
+		WithRecordedAt:  !sinceTimestamp.IsZero(),
+		WithUserDetails: withTimesheet,
+		WithMovedTo:     config.FollowMovedIssues == "true",
+		Group:           groupPath != "",
+	}
+
+	withMergeRequests := config.WithMergeRequests == "true"
+
+	pushgatewayAddr := config.PushgatewayURL
+	if pushgatewayAddr == "" {
+		pushgatewayAddr = config.PushgatewayAddr
+	}
+	slackWebhookURL := config.SlackWebhookURL
+	webhookURL := config.WebhookURL
+
+	// BASELINE_FILE compares the user and all users reports with a JSON report saved by an earlier run
+	var baseline *SpentTimeReport
+	if baselineFile := config.BaselineFile; baselineFile != "" {
+		baseline, err = loadBaseline(baselineFile)
+		if err != nil {
+			return exitErrorf(exitConfig, "Failed to load baseline: %v", err)
+		}
+	}
+
+	// bot and system users are never reported, nor the ghost user unless INCLUDE_GHOST_USER opts back in
+	excludedUsernames := splitList(config.ExcludeUsers)
+	if config.IncludeGhostUser != "true" && !slices.Contains(excludedUsernames, ghostUsername) {
+		excludedUsernames = append(excludedUsernames, ghostUsername)
+	}
+	jobName := config.JobName
+	if jobName == "" {
+		jobName = "gitlab_issues_data"
+	}
+	// Several categories of non dev time can be tracked, comma-separated
+	reportingIssues := splitList(config.GitlabReportingIssue)
+	var reportingPattern *regexp.Regexp
+	if reportingPatternEnv := config.GitlabReportingPattern; reportingPatternEnv != "" {
+		reportingPattern, err = regexp.Compile(reportingPatternEnv)
+		if err != nil {
+			return exitErrorf(exitConfig, "GITLAB_REPORTING_PATTERN must be a valid regular expression: %v", err)
+		}
+	}
+
+	userAgent := config.UserAgent
+	if userAgent == "" {
+		userAgent = "gitlab-issues-data"
+	}
+
+	gitlabAPIUrl := gitlabHost + "/api/v4"
+	// GraphQL requests can be sent elsewhere, such as a proxy or a local server returning canned responses
+	gitlabGraphQLUrl := config.GitlabGraphQLURL
+	if gitlabGraphQLUrl == "" {
+		gitlabGraphQLUrl = gitlabHost + "/api/graphql"
+	}
+	// GITLAB_HOST alone would let a proxy in GITLAB_GRAPHQL_URL share the entries of the instance
+	if cache != nil {
+		cache.endpoint = gitlabGraphQLUrl
+	}
+
+	insecureSkipVerify := config.InsecureSkipVerify == "true"
+	if insecureSkipVerify {
+		logger.Warn("INSECURE_SKIP_VERIFY is set, TLS certificates are NOT verified and the token can be intercepted, only use it while setting up CA_CERT_FILE")
+	}
+	transport, err := newBaseTransport(config.CACertFile, insecureSkipVerify)
+	if err != nil {
+		return exitErrorf(exitConfig, "CA_CERT_FILE must be a PEM file of certificates: %v", err)
+	}
+
+	// the webhook gets the proxy and certificates but never the Gitlab credentials
+	webhookTransport := &userAgentTransport{userAgent: userAgent, next: transport}
+
+	newGitlabClient := gitlab.NewClient
+	if useOAuth {
+		transport = &oauth2.Transport{Source: newOAuthTokenSource(gitlabHost, oauthClientID, oauthClientSecret, oauthRefreshToken, oauthRefreshTokenFile, transport), Base: transport}
+		newGitlabClient = gitlab.NewOAuthClient
+	}
+
+	// Get current username with the personal access token
+	gitlabClient, err := newGitlabClient(apiToken, gitlab.WithBaseURL(gitlabAPIUrl), gitlab.WithHTTPClient(&http.Client{Transport: transport}))
+	if err != nil {
+		return exitErrorf(exitConfig, "Failed to create client: %v", err)
+	}
+	gitlabClient.UserAgent = userAgent
+
+	// A dry run sends nothing to Gitlab, REST calls included, the token owner is the first of USERNAMES or a placeholder
+	dryRun := config.DryRun == "true"
+	var currentUser *gitlab.User
+	if dryRun {
+		currentUser = &gitlab.User{Username: dryRunUsername}
+		if usernames := splitList(config.Usernames); len(usernames) > 0 {
+			currentUser.Username = usernames[0]
+		}
+		logger.Info("Dry run, the token owner is not looked up", "username", currentUser.Username)
+	} else {
+		var currentUserResponse *gitlab.Response
+		currentUser, currentUserResponse, err = gitlabClient.Users.CurrentUser()
+		if err != nil {
+			return exitErrorf(gitlabExitCode(err), "Failed to get current user: %v", err)
+		}
+
+		// A token without read_api returns empty results rather than errors, warn early about it
+		if token, _, err := gitlabClient.PersonalAccessTokens.GetSinglePersonalAccessToken(); err == nil {
+			hasReadScope := false
+			for _, scope := range token.Scopes {
+				if scope == "read_api" || scope == "api" {
+					hasReadScope = true
+				}
+			}
+			if !hasReadScope {
+				logger.Warn("GITLAB_TOKEN lacks read_api or api, needed to read timelogs", "scopes", token.Scopes)
+			}
+		}
+
+		// Date filtering relies on the local clock, compare it with Gitlab's to catch a wrong system time
+		if clockSkewThreshold > 0 {
+			serverTime, err := http.ParseTime(currentUserResponse.Header.Get("Date"))
+			if err != nil {
+				logger.Warn("Could not check clock skew, Gitlab response has no valid Date header")
+			} else if skew := time.Since(serverTime); skew > clockSkewThreshold || -skew > clockSkewThreshold {
+				logger.Warn("Local clock differs from Gitlab's, reported dates may be wrong", "skew", skew.Round(time.Second))
+			}
+		}
+	}
+
+	reportOptions := ReportOptions{
+		Capacities:          capacities,
+		VerifyTotals:        verifyTotals,
+		FilterLocation:      filterLocation,
+		OutputLocation:      outputLocation,
+		RoundingMode:        roundingMode,
+		Language:            language,
+		ShowUpdatedAt:       config.ShowUpdatedAt == "true",
+		KeyBy:               keyBy,
+		IssueDetailMinHours: float32(issueDetailMinHours),
+		GitlabHost:          gitlabHost,
+		Output:              os.Stdout,
+		MixedIssues:         mixedIssues,
+		CollapseEntries:     config.CollapseEntries == "true",
+		Cumulative:          cumulative,
+		RunTag:              config.RunTag,
+		Breakdown:           breakdown,
+		Sparkline:           config.Sparkline == "true",
+		ShowEmptyDays:       config.ShowEmptyDays == "true",
+		SortBy:              sortBy,
+		TopN:                topN,
+		Usernames:           splitList(config.Usernames),
+		ShowZero:            config.ShowZero == "true",
+		CSVDelimiter:        csvDelimiter,
+		CSVDecimalComma:     csvDecimalSeparator == ",",
+		ExcelBOM:            config.ExcelBOM == "true" || config.CSVBOM == "true",
+		TimeOffLabel:        config.TimeOffLabel,
+	}
+
+	// Gitlab REST API does not provide timelog object on issues with who log what, only the graphQL API does that
+	var printQuery *printQueryTransport
+	if dryRun || config.PrintQuery == "true" {
+		printQuery = &printQueryTransport{w: os.Stderr, dryRun: dryRun, secrets: secrets}
+	}
+	graphQLClient := newGraphQLClient(gitlabGraphQLUrl, userAgent, maxRetries, transport, printQuery)
+
+	// Get go context
+	ctx := context.Background()
+	if requestTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, requestTimeout)
+		defer cancel()
+	}
+
+	projectPaths := projectIds
+	if scope == "my-projects" && dryRun {
+		// listing the projects is a REST call too, the query is printed once with a placeholder path
+		projectPaths = []string{dryRunProjectPath}
+	} else if scope == "my-projects" {
+		projectPaths, err = getMemberProjectPaths(gitlabClient)
+		if err != nil {
+			return exitErrorf(gitlabExitCode(err), "Failed to list projects: %v", err)
+		}
+		logger.Info("Reporting on the projects the user is a member of", "projects", len(projectPaths), "username", currentUser.Username)
+	}
+	reportOptions.MultipleProjects = len(projectPaths) > 1
+	// The group is fetched as a single path, its issues belong to many projects
+	if groupPath != "" {
+		projectPaths = []string{groupPath}
+		reportOptions.MultipleProjects = true
+	}
+
+	if serveAddr := config.ServeAddr; serveAddr != "" {
+		server := &reportServer{
+			fetch: func(ctx context.Context) (*TimelogData, error) {
+				timelogData, err := fetchProjectsTimelogs(projectPaths, chunkBoundaries, concurrency, retryOnEmpty, withMergeRequests, apiToken, queryOptions, cache, graphQLClient, ctx)
+				if err == nil {
+					excludeUsersTimelogs(timelogData, excludedUsernames)
+				}
+				return timelogData, err
+			},
+			cacheTTL:        serveCacheTTL,
+			requestTimeout:  requestTimeout,
+			defaultDays:     daysNum,
+			trackingIssues:  reportingIssues,
+			trackingPattern: reportingPattern,
+			reportOptions:   reportOptions,
+			lock:            make(chan struct{}, 1),
+		}
+		if err := server.listenAndServe(serveAddr); err != nil {
+			return exitErrorf(exitFailure, "Failed to serve reports: %v", err)
+		}
+		return nil
+	}
+
+	timelogData, err := fetchProjectsTimelogs(projectPaths, chunkBoundaries, concurrency, retryOnEmpty, withMergeRequests, apiToken, queryOptions, cache, graphQLClient, ctx)
+	if errors.Is(err, errDryRun) {
+		return nil
+	}
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return exitErrorf(exitNetwork, "Timed out, REQUEST_TIMEOUT of %s exceeded: %v", requestTimeout, err)
+		}
+		if errors.Is(err, errProjectNotFound) {
+			return exitErrorf(exitNotFound, "%v", err)
+		}
+		return exitErrorf(exitNetwork, "%v", err)
+	}
+	if excluded := excludeUsersTimelogs(timelogData, excludedUsernames); excluded > 0 {
+		logger.Info("Excluded the timelogs of EXCLUDE_USERS", "timelogs", excluded, "usernames", strings.Join(excludedUsernames, ", "))
+	}
+	stats := newRunStats()
+	stats.PagesFetched = timelogData.PagesFetched
+
+	hoursPerCategory := make(map[string]map[string]float32)
+	// Reports working for both modes take an empty username for all users
+	reportUsername := currentUser.Username
+	if getAllUsers != "" {
+		reportUsername = ""
+	}
+
+	// every format is written from the same fetch, the text report lines and the reports added after them go where the text format goes
+	var textOutput io.Writer = diagnostics
+	var textColor bool
+	for i, outputFormat := range outputFormats {
+		// timelogs are counted once by the first format, the later ones only add their detail lines
+		formatStats := stats
+		if i > 0 {
+			formatStats = newRunStats()
+		}
+
+		// OUTPUT_FILE replaces stdout for every format unless TEE is set, diagnostics stay on stderr
+		reportFile := os.Stdout
+		var reportWriter io.Writer = os.Stdout
+		if outputFile := config.OutputFile; outputFile != "" {
+			outputPath := strings.ReplaceAll(outputFile, "{date}", end.Format("2006-01-02"))
+			// several formats each get their own file, named after OUTPUT_FILE with the extension of the format
+			if len(outputFormats) > 1 {
+				outputPath = strings.TrimSuffix(outputPath, filepath.Ext(outputPath)) + outputFormatExtensions[outputFormat]
+			}
+			if err := os.MkdirAll(filepath.Dir(outputPath), 0o755); err != nil {
+				return exitErrorf(exitFailure, "Failed to create the directory of OUTPUT_FILE: %v", err)
+			}
+			file, err := os.Create(outputPath)
+			if err != nil {
+				return exitErrorf(exitFailure, "Failed to create OUTPUT_FILE: %v", err)
+			}
+			checked := &checkedWriter{w: file}
+			defer func() {
+				err := file.Close()
+				if checked.err != nil {
+					err = checked.err
+				}
+				if err != nil && runErr == nil {
+					runErr = exitErrorf(exitFailure, "Failed to write OUTPUT_FILE %s: %v", outputPath, err)
+				}
+			}()
+			reportFile = file
+			reportWriter = checked
+			// stdout gets the same bytes as the file, so colors follow the file and are never written in auto mode
+			if config.Tee == "true" {
+				reportWriter = io.MultiWriter(checked, os.Stdout)
+			}
+			logger.Info("Writing the report to a file", "path", outputPath, "format", outputFormat)
+		}
+		reportOptions.Output = reportWriter
+		reportOptions.JSONOutput = outputFormat == "json"
+
+		// text report lines are written where the log package writes, that is the file checked for a terminal
+		colorOutput = useColor(colorMode, reportFile)
+		// the other formats write a single document, the text lines printed next to it such as the run tag stay on stderr to keep it valid
+		log.SetOutput(diagnostics)
+		if outputFormat == "text" {
+			textOutput = &redactingWriter{w: reportWriter, secrets: secrets}
+			textColor = colorOutput
+			log.SetOutput(textOutput)
+		}
+
+		if runTag := config.RunTag; runTag != "" {
+			log.Printf("Run tag: %s", runTag)
+		}
+
+		if !sinceTimestamp.IsZero() {
+			getSpentTimeSince(sinceTimestamp, reportUsername, timelogData, reportOptions, formatStats)
+		} else if groupBy == "date" {
+			if err := getDateSpentTime(start, end, timelogData, reportOptions, outputFormat == "json", formatStats); err != nil {
+				return exitErrorf(exitFailure, "Failed to write date report: %v", err)
+			}
+		} else if outputFormat == "csv" {
+			entries := getTimesheetEntries(start, end, reportUsername, timelogData, reportOptions, formatStats)
+			if err := writeCSVReport(reportOptions.Output, entries, reportingIssues, reportingPattern, reportOptions); err != nil {
+				return exitErrorf(exitFailure, "Failed to write csv report: %v", err)
+			}
+		} else if _, isTimesheet := timesheetFormats[outputFormat]; isTimesheet {
+			entries := getTimesheetEntries(start, end, reportUsername, timelogData, reportOptions, formatStats)
+			if err := writeTimesheet(reportOptions.Output, outputFormat, timesheetClient, reportOptions.RunTag, entries); err != nil {
+				return exitErrorf(exitFailure, "Failed to write %s timesheet: %v", outputFormat, err)
+			}
+		} else if outputFormat == "markdown" {
+			// the single user report has no dev split, the all users one limited to that user does
+			markdownOptions := reportOptions
+			if getAllUsers == "" {
+				markdownOptions.Usernames = []string{currentUser.Username}
+			}
+			report := getAllUsersSpentTime(start, end, reportingIssues, reportingPattern, timelogData, markdownOptions, formatStats)
+			if err := writeMarkdownReport(markdownOptions.Output, report, markdownOptions); err != nil {
+				return exitErrorf(exitFailure, "Failed to write markdown report: %v", err)
+			}
+			hoursPerCategory["dev"], hoursPerCategory["non-dev"] = report.DevHours, report.NonDevHours
+		} else if groupBy == "epic" {
+			getEpicSpentTime(start, end, reportUsername, timelogData, reportOptions, formatStats)
+		} else if groupBy == "milestone" {
+			getMilestoneSpentTime(start, end, reportUsername, timelogData, reportOptions, formatStats)
+		} else if groupBy == "iteration" {
+			getIterationSpentTime(start, end, reportUsername, timelogData, reportOptions, formatStats)
+		} else if groupBy == "issue" {
+			if err := getIssueSpentTime(start, end, reportUsername, timelogData, reportOptions, outputFormat == "json", formatStats); err != nil {
+				return exitErrorf(exitFailure, "Failed to write issue report: %v", err)
+			}
+		} else if getAllUsers == "" {
+			report := getUserSpentTime(start, end, currentUser.Username, timelogData, reportOptions, formatStats)
+			if err := printUserReport(report, reportOptions, formatStats); err != nil {
+				return exitErrorf(exitFailure, "Failed to write report: %v", err)
+			}
+			if milestoneSubtotals && outputFormat == "text" {
+				getMilestoneSpentTime(start, end, reportUsername, timelogData, reportOptions, newRunStats())
+			}
+			if baseline != nil {
+				logBaselineDeltas(userSpentTimeReport(report, reportOptions), *baseline, reportOptions)
+			}
+			hoursPerCategory["all"] = map[string]float32{currentUser.Username: report.TotalHours}
+		} else {
+			report := getAllUsersSpentTime(start, end, reportingIssues, reportingPattern, timelogData, reportOptions, formatStats)
+			if err := printAllUsersReport(report, reportOptions, formatStats); err != nil {
+				return exitErrorf(exitFailure, "Failed to write report: %v", err)
+			}
+			if milestoneSubtotals && outputFormat == "text" {
+				getMilestoneSpentTime(start, end, reportUsername, timelogData, reportOptions, newRunStats())
+			}
+			if baseline != nil {
+				logBaselineDeltas(allUsersSpentTimeReport(report, reportOptions), *baseline, reportOptions)
+			}
+			hoursPerCategory["dev"], hoursPerCategory["non-dev"] = report.DevHours, report.NonDevHours
+		}
+		if i > 0 {
+			stats.DetailLines += formatStats.DetailLines
+		}
+	}
+	log.SetOutput(textOutput)
+	colorOutput = textColor
+
+	if pushgatewayAddr != "" && len(hoursPerCategory) > 0 {
+		pushMetrics(pushgatewayAddr, jobName, hoursPerCategory)
+	}
+
+	if slackWebhookURL != "" {
+		slackOptions := reportOptions
+		if reportUsername != "" {
+			slackOptions.Usernames = []string{reportUsername}
+		}
+		postSlackSummary(slackWebhookURL, getAllUsersSpentTime(start, end, reportingIssues, reportingPattern, timelogData, slackOptions, newRunStats()), slackOptions)
+	}
+
+	// WEBHOOK_URL gets the JSON document of the user or all users report whatever the output format
+	if webhookURL != "" {
+		var webhookReport SpentTimeReport
+		if getAllUsers == "" {
+			webhookReport = userSpentTimeReport(getUserSpentTime(start, end, currentUser.Username, timelogData, reportOptions, newRunStats()), reportOptions)
+		} else {
+			webhookReport = allUsersSpentTimeReport(getAllUsersSpentTime(start, end, reportingIssues, reportingPattern, timelogData, reportOptions, newRunStats()), reportOptions)
+		}
+		postWebhook(webhookURL, webhookAuthName, webhookAuthValue, webhookReport, maxRetries, webhookTransport)
+	}
+
+	if agingReport {
+		getAgingReport(agingThreshold, timelogData, reportOptions)
+	}
+
+	if estimateReport {
+		getEstimateReport(start, end, reportUsername, timelogData, reportOptions, newRunStats())
+	}
+
+	if verifyNotesTime {
+		mismatches, err := verifyNotes(timelogData, gitlabClient, excludedUsernames, reportOptions)
+		if err != nil {
+			return exitErrorf(gitlabExitCode(err), "Failed to verify the notes: %v", err)
+		}
+		if mismatches > 0 {
+			logger.Warn("Time spent in notes does not match the timelogs", "issues", mismatches)
+		}
+	}
+
+	if costReport {
+		costOptions := reportOptions
+		if reportUsername != "" {
+			costOptions.Usernames = []string{reportUsername}
+		}
+		getCostReport(start, end, reportingIssues, reportingPattern, timelogData, costOptions, rates, currency, newRunStats())
+	}
+
+	if config.Stats == "true" {
+		getDailyStats(start, end, reportUsername, timelogData, reportOptions, newRunStats())
+	}
+
+	if velocity {
+		getVelocity(start, end, reportUsername, timelogData, reportOptions, newRunStats())
+	}
+
+	if showStats {
+		logStats(stats, reportOptions)
+	}
+	if skipped := stats.Excluded[malformedSpentAt]; skipped > 0 {
+		logger.Warn("Timelogs skipped because of a malformed spentAt", "count", skipped)
+	}
+
+	if requireAllUsersLogged {
+		expectedUsernames, err := getExpectedUsernames(membersSource, config.Usernames, projectPaths, groupPath, gitlabClient)
+		if err != nil {
+			return exitErrorf(gitlabExitCode(err), "Failed to get users expected to log time: %v", err)
+		}
+
+		loggedUsernames := make(map[string]bool)
+		for _, entry := range getTimesheetEntries(start, end, "", timelogData, reportOptions, newRunStats()) {
+			loggedUsernames[entry.Username] = true
+		}
+
+		var missingUsernames []string
+		for _, username := range expectedUsernames {
+			// EXCLUDE_USERS and the ghost user have their timelogs dropped, they can never have logged time
+			if !loggedUsernames[username] && !slices.Contains(excludedUsernames, username) {
+				missingUsernames = append(missingUsernames, username)
+			}
+		}
+		if len(missingUsernames) > 0 {
+			return exitErrorf(exitNoData, "%d users logged no time from %s to %s: %s", len(missingUsernames), start.Format("2006-01-02"), end.Format("2006-01-02"), strings.Join(missingUsernames, ", "))
+		}
+	}
+
+	if budgetHours > 0 {
+		devHours := getBudgetDevHours(start, end, budgetCumulative, reportingIssues, reportingPattern, timelogData, reportOptions)
+		if err := checkBudget(devHours, budgetHours, warnThreshold, reportOptions); err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
@@ -0,0 +1,1490 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	gitlab "github.com/xanzy/go-gitlab"
+)
+
+// GraphQL request as received by the fake Gitlab
+type graphQLRequest struct {
+	Query     string                 `json:"query"`
+	Variables map[string]interface{} `json:"variables"`
+}
+
+// Fake Gitlab answering the REST calls made before fetching and every GraphQL query with respond
+type fakeGitlab struct {
+	*httptest.Server
+	mu       sync.Mutex
+	requests []graphQLRequest
+}
+
+func newFakeGitlab(t *testing.T, respond func(request graphQLRequest) string) *fakeGitlab {
+	t.Helper()
+	gitlab := &fakeGitlab{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v4/user", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"id": 1, "username": "alice"}`)
+	})
+	mux.HandleFunc("/api/v4/personal_access_tokens/self", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"id": 1, "scopes": ["read_api"]}`)
+	})
+	mux.HandleFunc("/api/graphql", func(w http.ResponseWriter, r *http.Request) {
+		var request graphQLRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			t.Errorf("could not decode GraphQL request: %v", err)
+		}
+		gitlab.mu.Lock()
+		gitlab.requests = append(gitlab.requests, request)
+		gitlab.mu.Unlock()
+		fmt.Fprintf(w, `{"data": %s}`, respond(request))
+	})
+	gitlab.Server = httptest.NewServer(mux)
+	t.Cleanup(gitlab.Close)
+
+	return gitlab
+}
+
+func (gitlab *fakeGitlab) graphQLRequests() []graphQLRequest {
+	gitlab.mu.Lock()
+	defer gitlab.mu.Unlock()
+
+	return append([]graphQLRequest(nil), gitlab.requests...)
+}
+
+type testTimelog struct {
+	username  string
+	spentAt   string
+	timeSpent int
+}
+
+type testIssue struct {
+	iid       string
+	title     string
+	timelogs  []testTimelog
+	milestone string
+	labels    []string
+	// seconds, only sent when an estimate is set
+	timeEstimate   int
+	totalTimeSpent int
+}
+
+// GraphQL project with these issues on a single page, not followed by any other
+func issuesPage(issues []testIssue, endCursor string) string {
+	type node map[string]interface{}
+	nodes := []node{}
+	for _, issue := range issues {
+		timelogs := []node{}
+		for _, timelog := range issue.timelogs {
+			timelogs = append(timelogs, node{
+				"timeSpent": timelog.timeSpent,
+				"spentAt":   timelog.spentAt,
+				"user":      node{"id": "gid://gitlab/User/" + timelog.username, "username": timelog.username},
+			})
+		}
+		issueNode := node{
+			"id":        "gid://gitlab/Issue/" + issue.iid,
+			"iid":       issue.iid,
+			"title":     issue.title,
+			"updatedAt": "2024-03-10T12:00:00Z",
+			"state":     "opened",
+			"timelogs":  node{"pageInfo": node{"hasNextPage": false, "endCursor": ""}, "nodes": timelogs},
+		}
+		if issue.timeEstimate != 0 {
+			issueNode["timeEstimate"] = issue.timeEstimate
+			issueNode["totalTimeSpent"] = issue.totalTimeSpent
+		}
+		if issue.milestone != "" {
+			issueNode["milestone"] = node{"title": issue.milestone}
+		}
+		if issue.labels != nil {
+			labels := []node{}
+			for _, label := range issue.labels {
+				labels = append(labels, node{"title": label})
+			}
+			issueNode["labels"] = node{"nodes": labels}
+		}
+		nodes = append(nodes, issueNode)
+	}
+	page := node{"project": node{"issues": node{
+		"pageInfo": node{"hasNextPage": endCursor != "", "endCursor": endCursor},
+		"nodes":    nodes,
+	}}}
+	content, _ := json.Marshal(page)
+
+	return string(content)
+}
+
+// Run the whole report against gitlab with env and return what was written to OUTPUT_FILE
+func runReport(t *testing.T, gitlab *fakeGitlab, env map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	emptyEnvFile := filepath.Join(dir, "empty.env")
+	if err := os.WriteFile(emptyEnvFile, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	outputFile := filepath.Join(dir, "report")
+
+	defaults := map[string]string{
+		"ENV_FILES":           emptyEnvFile,
+		"GITLAB_TOKEN":        "glpat-test",
+		"GITLAB_HOST":         gitlab.URL,
+		"GITLAB_PROJECT_PATH": "group/app",
+		"START_DATE":          "2024-03-01",
+		"END_DATE":            "2024-03-31",
+		"OUTPUT_FILE":         outputFile,
+		"LOG_LEVEL":           "error",
+	}
+	for key, value := range env {
+		defaults[key] = value
+	}
+	for key, value := range defaults {
+		t.Setenv(key, value)
+	}
+
+	args := os.Args
+	os.Args = []string{args[0]}
+	defer func() { os.Args = args }()
+	if err := run(); err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	// an empty OUTPUT_FILE leaves the report on stdout
+	if defaults["OUTPUT_FILE"] == "" {
+		return ""
+	}
+
+	content, err := os.ReadFile(defaults["OUTPUT_FILE"])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(content)
+}
+
+func decodeReport(t *testing.T, content string) SpentTimeReport {
+	t.Helper()
+	var report SpentTimeReport
+	if err := json.NewDecoder(strings.NewReader(content)).Decode(&report); err != nil {
+		t.Fatalf("could not decode report %q: %v", content, err)
+	}
+
+	return report
+}
+
+func TestRunReportEndToEnd(t *testing.T) {
+	gitlab := newFakeGitlab(t, func(request graphQLRequest) string {
+		return issuesPage([]testIssue{{iid: "1", title: "Feature", timelogs: []testTimelog{
+			{"alice", "2024-03-04T09:00:00Z", 5400},
+			{"bob", "2024-03-04T09:00:00Z", 3600},
+			{"alice", "2024-04-02T09:00:00Z", 3600},
+		}}}, "")
+	})
+
+	for _, format := range []string{"json", "text"} {
+		t.Run(format, func(t *testing.T) {
+			content := runReport(t, gitlab, map[string]string{"OUTPUT_FORMAT": format, "TIMEZONE": "Europe/Paris"})
+			if format == "text" {
+				if !strings.Contains(content, "for alice : 1.5h") {
+					t.Errorf("text report has no 1.5h total for alice:\n%s", content)
+				}
+				return
+			}
+
+			report := decodeReport(t, content)
+			if len(report.Users) != 1 || report.Users[0].Username != "alice" || report.Users[0].Hours != 1.5 {
+				t.Errorf("users = %+v, want alice with 1.5h", report.Users)
+			}
+			if len(report.Entries) != 1 || report.Entries[0].Date != "2024-03-04" {
+				t.Errorf("entries = %+v, want a single entry on 2024-03-04", report.Entries)
+			}
+		})
+	}
+}
+
+// Content written to stdout and stderr while f runs
+func captureStreams(t *testing.T, f func()) (string, string) {
+	t.Helper()
+	dir := t.TempDir()
+	stdout, err := os.Create(filepath.Join(dir, "stdout"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	stderr, err := os.Create(filepath.Join(dir, "stderr"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stdout.Close()
+	defer stderr.Close()
+
+	previousStdout, previousStderr := os.Stdout, os.Stderr
+	os.Stdout, os.Stderr = stdout, stderr
+	func() {
+		defer func() { os.Stdout, os.Stderr = previousStdout, previousStderr }()
+		f()
+	}()
+
+	stdoutContent, err := os.ReadFile(stdout.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	stderrContent, err := os.ReadFile(stderr.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(stdoutContent), string(stderrContent)
+}
+
+func TestReportStreams(t *testing.T) {
+	gitlab := newFakeGitlab(t, func(request graphQLRequest) string {
+		return issuesPage([]testIssue{{iid: "1", title: "Feature", timelogs: []testTimelog{{"alice", "2024-03-04T09:00:00Z", 5400}}}}, "")
+	})
+
+	t.Run("text report on stdout", func(t *testing.T) {
+		stdout, stderr := captureStreams(t, func() {
+			runReport(t, gitlab, map[string]string{"OUTPUT_FILE": "", "RUN_TAG": "nightly", "LOG_LEVEL": "info"})
+		})
+		if !strings.Contains(stdout, "Run tag: nightly") || !strings.Contains(stdout, "for alice : 1.5h") {
+			t.Errorf("stdout has no text report:\n%s", stdout)
+		}
+		if strings.Contains(stderr, "for alice") || !strings.Contains(stderr, "level=INFO") {
+			t.Errorf("stderr has report lines or no diagnostics:\n%s", stderr)
+		}
+	})
+
+	t.Run("text lines of a json report on stderr", func(t *testing.T) {
+		stdout, stderr := captureStreams(t, func() {
+			runReport(t, gitlab, map[string]string{"OUTPUT_FILE": "", "RUN_TAG": "nightly", "OUTPUT_FORMAT": "json"})
+		})
+		if report := decodeReport(t, stdout); len(report.Users) != 1 {
+			t.Errorf("users = %+v, want alice", report.Users)
+		}
+		if !strings.Contains(stderr, "Run tag: nightly") {
+			t.Errorf("stderr has no run tag:\n%s", stderr)
+		}
+	})
+}
+
+func TestTimezoneDayBoundaries(t *testing.T) {
+	tests := []struct {
+		name     string
+		timezone string
+		spentAt  string
+		// empty when the timelog falls out of the 2024-03-01 to 2024-03-31 window
+		wantDate string
+	}{
+		{"late UTC evening is the next day east of UTC", "Europe/Paris", "2024-03-04T23:30:00Z", "2024-03-05"},
+		{"early UTC morning is the previous day west of UTC", "America/New_York", "2024-03-05T02:00:00Z", "2024-03-04"},
+		{"same day in UTC", "UTC", "2024-03-04T23:30:00Z", "2024-03-04"},
+		{"last UTC hour of the window is past its end east of UTC", "Europe/Paris", "2024-03-31T22:30:00Z", ""},
+		{"first UTC hour after the window is within it west of UTC", "America/New_York", "2024-04-01T03:00:00Z", "2024-03-31"},
+		{"UTC day before the window is its first day east of UTC", "Asia/Tokyo", "2024-02-29T16:00:00Z", "2024-03-01"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			gitlab := newFakeGitlab(t, func(request graphQLRequest) string {
+				return issuesPage([]testIssue{{iid: "1", title: "Feature", timelogs: []testTimelog{{"alice", test.spentAt, 3600}}}}, "")
+			})
+			report := decodeReport(t, runReport(t, gitlab, map[string]string{"OUTPUT_FORMAT": "json", "TIMEZONE": test.timezone}))
+
+			if test.wantDate == "" {
+				if len(report.Entries) != 0 {
+					t.Errorf("entries = %+v, want none", report.Entries)
+				}
+				return
+			}
+			if len(report.Entries) != 1 || report.Entries[0].Date != test.wantDate {
+				t.Errorf("entries = %+v, want one on %s", report.Entries, test.wantDate)
+			}
+		})
+	}
+}
+
+func testTimelogData(issues ...Issue) *TimelogData {
+	var data TimelogData
+	data.Project.Issues.Nodes = issues
+	return &data
+}
+
+func testIssueWith(iid string, title string, timelogs ...Timelog) Issue {
+	issue := Issue{ID: "gid://gitlab/Issue/" + iid, IID: iid, Title: title, ProjectPath: "group/app"}
+	issue.Timelogs.Nodes = timelogs
+	return issue
+}
+
+func timelogAt(username string, spentAt string, seconds int) Timelog {
+	timelog := Timelog{TimeSpent: seconds, SpentAt: spentAt}
+	timelog.User.ID = "gid://gitlab/User/" + username
+	timelog.User.Username = username
+	return timelog
+}
+
+func mustLoadLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return loc
+}
+
+func day(t *testing.T, date string, loc *time.Location) time.Time {
+	t.Helper()
+	d, err := time.ParseInLocation("2006-01-02", date, loc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func TestGetUserSpentTime(t *testing.T) {
+	tests := []struct {
+		name       string
+		timezone   string
+		start, end string
+		timelogs   []Timelog
+		username   string
+		wantHours  float32
+		wantDays   map[string]float32
+	}{
+		{
+			name: "first and last day of the window are included", timezone: "UTC", start: "2024-03-01", end: "2024-03-31",
+			timelogs: []Timelog{
+				timelogAt("alice", "2024-02-29T23:59:59Z", 3600),
+				timelogAt("alice", "2024-03-01T00:00:00Z", 3600),
+				timelogAt("alice", "2024-03-31T23:59:59Z", 3600),
+				timelogAt("alice", "2024-04-01T00:00:00Z", 3600),
+			},
+			username: "alice", wantHours: 2, wantDays: map[string]float32{"2024-03-01": 1, "2024-03-31": 1},
+		},
+		{
+			name: "day of the spring forward DST transition", timezone: "Europe/Paris", start: "2024-03-31", end: "2024-03-31",
+			timelogs: []Timelog{
+				// 23:30 CET the day before
+				timelogAt("alice", "2024-03-30T22:30:00Z", 3600),
+				// 00:30 CET, before the clocks move
+				timelogAt("alice", "2024-03-30T23:30:00Z", 3600),
+				// 23:30 CEST, after they moved
+				timelogAt("alice", "2024-03-31T21:30:00Z", 1800),
+				// 00:30 CEST the day after
+				timelogAt("alice", "2024-03-31T22:30:00Z", 3600),
+			},
+			username: "alice", wantHours: 1.5, wantDays: map[string]float32{"2024-03-31": 1.5},
+		},
+		{
+			name: "day of the fall back DST transition", timezone: "Europe/Paris", start: "2024-10-27", end: "2024-10-27",
+			timelogs: []Timelog{
+				// 23:30 CEST the day before
+				timelogAt("alice", "2024-10-26T21:30:00Z", 3600),
+				// 00:30 CEST
+				timelogAt("alice", "2024-10-26T22:30:00Z", 3600),
+				// 23:30 CET, the day lasted 25 hours
+				timelogAt("alice", "2024-10-27T22:30:00Z", 1800),
+				// 00:30 CET the day after
+				timelogAt("alice", "2024-10-27T23:30:00Z", 3600),
+			},
+			username: "alice", wantHours: 1.5, wantDays: map[string]float32{"2024-10-27": 1.5},
+		},
+		{
+			name: "user without logs", timezone: "UTC", start: "2024-03-01", end: "2024-03-31",
+			timelogs: []Timelog{timelogAt("bob", "2024-03-04T09:00:00Z", 3600)},
+			username: "alice", wantHours: 0, wantDays: map[string]float32{},
+		},
+		{
+			name: "negative correction is subtracted", timezone: "UTC", start: "2024-03-01", end: "2024-03-31",
+			timelogs: []Timelog{
+				timelogAt("alice", "2024-03-04T09:00:00Z", 7200),
+				timelogAt("alice", "2024-03-05T09:00:00Z", -1800),
+			},
+			username: "alice", wantHours: 1.5, wantDays: map[string]float32{"2024-03-04": 2, "2024-03-05": -0.5},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			loc := mustLoadLocation(t, test.timezone)
+			reportOptions := ReportOptions{FilterLocation: loc, OutputLocation: loc}
+			data := testTimelogData(testIssueWith("1", "Feature", test.timelogs...))
+
+			report := getUserSpentTime(day(t, test.start, loc), day(t, test.end, loc), test.username, data, reportOptions, newRunStats())
+			if report.TotalHours != test.wantHours {
+				t.Errorf("TotalHours = %v, want %v", report.TotalHours, test.wantHours)
+			}
+			if !reflect.DeepEqual(report.HoursPerDay, test.wantDays) {
+				t.Errorf("HoursPerDay = %v, want %v", report.HoursPerDay, test.wantDays)
+			}
+		})
+	}
+}
+
+func TestGetAllUsersSpentTime(t *testing.T) {
+	tests := []struct {
+		name           string
+		usernames      []string
+		issues         []Issue
+		wantDevHours   map[string]float32
+		wantNonDev     map[string]float32
+		wantTotalHours map[string]float32
+	}{
+		{
+			name: "reporting issues are non dev time",
+			issues: []Issue{
+				testIssueWith("1", "Feature", timelogAt("alice", "2024-03-04T09:00:00Z", 7200), timelogAt("bob", "2024-03-04T09:00:00Z", 3600)),
+				testIssueWith("2", "Meetings", timelogAt("alice", "2024-03-05T09:00:00Z", 1800)),
+			},
+			wantDevHours:   map[string]float32{"alice": 2, "bob": 1},
+			wantNonDev:     map[string]float32{"alice": 0.5},
+			wantTotalHours: map[string]float32{"alice": 2.5, "bob": 1},
+		},
+		{
+			name:      "requested users without logs are reported with no time",
+			usernames: []string{"alice", "carol"},
+			issues: []Issue{
+				testIssueWith("1", "Feature", timelogAt("alice", "2024-03-04T09:00:00Z", 3600), timelogAt("bob", "2024-03-04T09:00:00Z", 3600)),
+			},
+			wantDevHours:   map[string]float32{"alice": 1, "carol": 0},
+			wantNonDev:     map[string]float32{"carol": 0},
+			wantTotalHours: map[string]float32{"alice": 1, "carol": 0},
+		},
+		{
+			name: "timelogs out of the window are left out",
+			issues: []Issue{
+				testIssueWith("1", "Feature", timelogAt("alice", "2024-02-29T23:00:00Z", 3600), timelogAt("bob", "2024-04-01T00:00:00Z", 3600)),
+			},
+			wantDevHours:   map[string]float32{},
+			wantNonDev:     map[string]float32{},
+			wantTotalHours: map[string]float32{},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			reportOptions := ReportOptions{FilterLocation: time.UTC, OutputLocation: time.UTC, Usernames: test.usernames}
+			report := getAllUsersSpentTime(day(t, "2024-03-01", time.UTC), day(t, "2024-03-31", time.UTC), []string{"Meetings"}, nil, testTimelogData(test.issues...), reportOptions, newRunStats())
+
+			if !reflect.DeepEqual(report.DevHours, test.wantDevHours) {
+				t.Errorf("DevHours = %v, want %v", report.DevHours, test.wantDevHours)
+			}
+			if !reflect.DeepEqual(report.NonDevHours, test.wantNonDev) {
+				t.Errorf("NonDevHours = %v, want %v", report.NonDevHours, test.wantNonDev)
+			}
+			if !reflect.DeepEqual(report.TotalHours, test.wantTotalHours) {
+				t.Errorf("TotalHours = %v, want %v", report.TotalHours, test.wantTotalHours)
+			}
+		})
+	}
+}
+
+func TestGetTimelogsPagination(t *testing.T) {
+	gitlab := newFakeGitlab(t, func(request graphQLRequest) string {
+		// the remaining timelogs of an issue
+		if strings.Contains(request.Query, "node: issue(iid: $iid)") {
+			return `{"project": {"node": {"timelogs": {
+				"pageInfo": {"hasNextPage": false, "endCursor": ""},
+				"nodes": [{"timeSpent": 1800, "spentAt": "2024-03-05T09:00:00Z", "user": {"id": "gid://gitlab/User/1", "username": "alice"}}]
+			}}}}`
+		}
+		if request.Variables["after"] == "issues-1" {
+			return issuesPage([]testIssue{{iid: "2", title: "Second", timelogs: []testTimelog{{"bob", "2024-03-06T09:00:00Z", 3600}}}}, "")
+		}
+		return `{"project": {"issues": {
+			"pageInfo": {"hasNextPage": true, "endCursor": "issues-1"},
+			"nodes": [{"id": "gid://gitlab/Issue/1", "iid": "1", "title": "First", "timelogs": {
+				"pageInfo": {"hasNextPage": true, "endCursor": "timelogs-1"},
+				"nodes": [{"timeSpent": 3600, "spentAt": "2024-03-04T09:00:00Z", "user": {"id": "gid://gitlab/User/1", "username": "alice"}}]
+			}}]
+		}}}`
+	})
+
+	client := newGraphQLClient(gitlab.URL+"/api/graphql", "test", 0, http.DefaultTransport, nil)
+	data, err := getTimelogs("group/app", "glpat-test", QueryOptions{OperationName: "test"}, client, context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if data.PagesFetched != 3 {
+		t.Errorf("PagesFetched = %d, want 2 issue pages and 1 timelog page", data.PagesFetched)
+	}
+	issues := data.Project.Issues.Nodes
+	if len(issues) != 2 {
+		t.Fatalf("got %d issues, want 2", len(issues))
+	}
+	if len(issues[0].Timelogs.Nodes) != 2 || issues[0].Timelogs.Nodes[1].TimeSpent != 1800 {
+		t.Errorf("timelogs of the first issue = %+v, want its second page appended", issues[0].Timelogs.Nodes)
+	}
+	if issues[0].ProjectPath != "group/app" || issues[1].ProjectPath != "group/app" {
+		t.Errorf("project paths = %q, %q, want group/app", issues[0].ProjectPath, issues[1].ProjectPath)
+	}
+
+	var cursors []string
+	for _, request := range gitlab.graphQLRequests() {
+		if after, ok := request.Variables["after"].(string); ok {
+			cursors = append(cursors, after)
+		}
+	}
+	if !reflect.DeepEqual(cursors, []string{"timelogs-1", "issues-1"}) {
+		t.Errorf("after cursors sent = %v, want the timelogs then the issues cursor", cursors)
+	}
+}
+
+func TestWithinWindow(t *testing.T) {
+	paris := mustLoadLocation(t, "Europe/Paris")
+	start, end := day(t, "2024-03-04", paris), day(t, "2024-03-08", paris)
+	tests := []struct {
+		name    string
+		spentAt string
+		wantOK  bool
+		wantDay string
+		// Excluded key counted when the timelog is left out
+		wantExcluded string
+	}{
+		{"23:59 the day before the start", "2024-03-03T23:59:00+01:00", false, "", "date window"},
+		{"00:01 on the start day", "2024-03-04T00:01:00+01:00", true, "2024-03-04", ""},
+		// Gitlab returns UTC, this is 00:01 in Paris on the start day
+		{"00:01 on the start day written in UTC", "2024-03-03T23:01:00Z", true, "2024-03-04", ""},
+		{"23:59 on the end day", "2024-03-08T23:59:00+01:00", true, "2024-03-08", ""},
+		{"00:01 the day after the end", "2024-03-09T00:01:00+01:00", false, "", "date window"},
+		{"malformed spentAt", "2024-03-05", false, "", malformedSpentAt},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			stats := newRunStats()
+			issue := testIssueWith("1", "Feature")
+			_, gotDay, ok := withinWindow(issue, timelogAt("alice", test.spentAt, 3600), start, end, paris, stats)
+			if ok != test.wantOK || gotDay != test.wantDay {
+				t.Errorf("withinWindow = %q, %v, want %q, %v", gotDay, ok, test.wantDay, test.wantOK)
+			}
+			if test.wantExcluded != "" && stats.Excluded[test.wantExcluded] != 1 {
+				t.Errorf("Excluded = %v, want one %q", stats.Excluded, test.wantExcluded)
+			}
+		})
+	}
+}
+
+func TestRoundHours(t *testing.T) {
+	tests := []struct {
+		hours       float32
+		wantHalfUp  float32
+		wantBankers float32
+	}{
+		// ties round up or to the even tenth
+		{0.25, 0.3, 0.2},
+		{0.35, 0.4, 0.4},
+		{0.15, 0.2, 0.2},
+		{2.45, 2.5, 2.4},
+		{2.55, 2.6, 2.6},
+		// negative corrections round away from zero or to the even tenth
+		{-0.25, -0.3, -0.2},
+		{-0.35, -0.4, -0.4},
+		{-2.45, -2.5, -2.4},
+		// just off a tie is never one
+		{0.2499, 0.2, 0.2},
+		{0.2501, 0.3, 0.3},
+		{-0.2501, -0.3, -0.3},
+		// float32 sums of seconds are snapped before rounding, 3 minutes is a tie
+		{float32(180) / 3600, 0.1, 0},
+		{float32(900) / 3600, 0.3, 0.2},
+		{0, 0, 0},
+	}
+	for _, test := range tests {
+		t.Run(fmt.Sprint(test.hours), func(t *testing.T) {
+			if got := roundHours(test.hours, "half-up"); got != test.wantHalfUp {
+				t.Errorf("half-up = %v, want %v", got, test.wantHalfUp)
+			}
+			if got := roundHours(test.hours, "bankers"); got != test.wantBankers {
+				t.Errorf("bankers = %v, want %v", got, test.wantBankers)
+			}
+			// the default formatting is left to Printf
+			if got := roundHours(test.hours, ""); got != test.hours {
+				t.Errorf("no mode = %v, want %v unchanged", got, test.hours)
+			}
+		})
+	}
+}
+
+func TestLabelFiltersSentToGitlab(t *testing.T) {
+	tests := []struct {
+		name              string
+		labels            string
+		excludeLabels     string
+		wantLabelName     []interface{}
+		wantNotLabelName  []interface{}
+		wantFilterClauses []string
+		// clauses the query must not have
+		wantNoClauses []string
+	}{
+		{
+			name: "labels and exclusions", labels: "billable, client", excludeLabels: "internal",
+			wantLabelName: []interface{}{"billable", "client"}, wantNotLabelName: []interface{}{"internal"},
+			wantFilterClauses: []string{"labelName: $labelName", "not: { labelName: $notLabelName }"},
+		},
+		{
+			name: "labels only", labels: "billable",
+			wantLabelName:     []interface{}{"billable"},
+			wantFilterClauses: []string{"labelName: $labelName"},
+			wantNoClauses:     []string{"not:", "$notLabelName"},
+		},
+		{
+			name: "exclusions only", excludeLabels: "internal,wontfix",
+			wantNotLabelName:  []interface{}{"internal", "wontfix"},
+			wantFilterClauses: []string{"not: { labelName: $notLabelName }"},
+			wantNoClauses:     []string{"labelName: $labelName", "$labelName: [String]"},
+		},
+		{
+			name:          "no label filter",
+			wantNoClauses: []string{"labelName", "not:"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			gitlab := newFakeGitlab(t, func(request graphQLRequest) string {
+				return issuesPage(nil, "")
+			})
+			runReport(t, gitlab, map[string]string{"OUTPUT_FORMAT": "json", "LABELS": test.labels, "EXCLUDE_LABELS": test.excludeLabels})
+
+			requests := gitlab.graphQLRequests()
+			if len(requests) != 1 {
+				t.Fatalf("got %d GraphQL requests, want 1", len(requests))
+			}
+			request := requests[0]
+			if got := request.Variables["labelName"]; !reflect.DeepEqual(got, nilIfEmpty(test.wantLabelName)) {
+				t.Errorf("labelName = %v, want %v", got, test.wantLabelName)
+			}
+			if got := request.Variables["notLabelName"]; !reflect.DeepEqual(got, nilIfEmpty(test.wantNotLabelName)) {
+				t.Errorf("notLabelName = %v, want %v", got, test.wantNotLabelName)
+			}
+			for _, clause := range test.wantFilterClauses {
+				if !strings.Contains(request.Query, clause) {
+					t.Errorf("query has no %q:\n%s", clause, request.Query)
+				}
+			}
+			for _, clause := range test.wantNoClauses {
+				if strings.Contains(request.Query, clause) {
+					t.Errorf("query has %q:\n%s", clause, request.Query)
+				}
+			}
+		})
+	}
+}
+
+// A variable that is not sent decodes as nil rather than an empty list
+func nilIfEmpty(values []interface{}) interface{} {
+	if len(values) == 0 {
+		return nil
+	}
+	return values
+}
+
+func TestCumulativeDetailLines(t *testing.T) {
+	gitlab := newFakeGitlab(t, func(request graphQLRequest) string {
+		return issuesPage([]testIssue{
+			{iid: "1", title: "Feature", timelogs: []testTimelog{
+				{"alice", "2024-03-05T09:00:00Z", 3600},
+				{"bob", "2024-03-04T09:00:00Z", 7200},
+			}},
+			{iid: "2", title: "Bugfix", timelogs: []testTimelog{{"alice", "2024-03-04T08:00:00Z", 1800}}},
+		}, "")
+	})
+
+	tests := []struct {
+		cumulativeBy string
+		want         []string
+	}{
+		{"all", []string{
+			"0.5h [cum 0.5h] at 2024-03-04 by alice - #2: Bugfix",
+			"2.0h [cum 2.5h] at 2024-03-04 by bob - #1: Feature",
+			"1.0h [cum 3.5h] at 2024-03-05 by alice - #1: Feature",
+		}},
+		{"user", []string{
+			"0.5h [cum 0.5h] at 2024-03-04 by alice - #2: Bugfix",
+			"2.0h [cum 2.0h] at 2024-03-04 by bob - #1: Feature",
+			"1.0h [cum 1.5h] at 2024-03-05 by alice - #1: Feature",
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.cumulativeBy, func(t *testing.T) {
+			content := runReport(t, gitlab, map[string]string{"ALL_USERS": "true", "CUMULATIVE": "true", "CUMULATIVE_BY": test.cumulativeBy})
+
+			var lines []string
+			for _, line := range strings.Split(content, "\n") {
+				if strings.Contains(line, "[cum ") {
+					// the issue link is left out
+					line, _, _ = strings.Cut(line, " http")
+					lines = append(lines, line)
+				}
+			}
+			if !reflect.DeepEqual(lines, test.want) {
+				t.Errorf("detail lines = %q, want %q\n%s", lines, test.want, content)
+			}
+		})
+	}
+}
+
+func TestTeeOutput(t *testing.T) {
+	gitlab := newFakeGitlab(t, func(request graphQLRequest) string {
+		return issuesPage([]testIssue{{iid: "1", title: "Feature", timelogs: []testTimelog{{"alice", "2024-03-04T09:00:00Z", 5400}}}}, "")
+	})
+
+	for _, format := range []string{"json", "text"} {
+		t.Run(format, func(t *testing.T) {
+			var content string
+			stdout, _ := captureStreams(t, func() {
+				content = runReport(t, gitlab, map[string]string{"OUTPUT_FORMAT": format, "TEE": "true"})
+			})
+			if !strings.Contains(content, "alice") {
+				t.Errorf("OUTPUT_FILE has no report:\n%s", content)
+			}
+			if stdout != content {
+				t.Errorf("stdout = %q, want the OUTPUT_FILE content %q", stdout, content)
+			}
+		})
+	}
+}
+
+func TestSeveralOutputFormats(t *testing.T) {
+	gitlab := newFakeGitlab(t, func(request graphQLRequest) string {
+		return issuesPage([]testIssue{{iid: "1", title: "Feature", timelogs: []testTimelog{{"alice", "2024-03-04T09:00:00Z", 5400}}}}, "")
+	})
+	dir := t.TempDir()
+
+	// OUTPUT_FILE keeps its name and takes the extension of each format
+	content := runReport(t, gitlab, map[string]string{"OUTPUT_FORMAT": "json, csv,text", "OUTPUT_FILE": filepath.Join(dir, "report.json")})
+	if report := decodeReport(t, content); len(report.Users) != 1 || report.Users[0].Hours != 1.5 {
+		t.Errorf("users = %+v, want alice with 1.5h", report.Users)
+	}
+	for _, file := range []struct{ name, want string }{{"report.csv", "alice"}, {"report.txt", "for alice : 1.5h"}} {
+		content, err := os.ReadFile(filepath.Join(dir, file.name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(content), file.want) {
+			t.Errorf("%s has no %q:\n%s", file.name, file.want, content)
+		}
+	}
+}
+
+func TestCSVByteOrderMark(t *testing.T) {
+	gitlab := newFakeGitlab(t, func(request graphQLRequest) string {
+		return issuesPage([]testIssue{{iid: "1", title: "Fonctionnalité", timelogs: []testTimelog{{"alice", "2024-03-04T09:00:00Z", 3600}}}}, "")
+	})
+
+	for _, env := range []string{"EXCEL_BOM", "CSV_BOM"} {
+		t.Run(env, func(t *testing.T) {
+			content := runReport(t, gitlab, map[string]string{"OUTPUT_FORMAT": "csv", env: "true"})
+			if !strings.HasPrefix(content, "\uFEFF") {
+				t.Errorf("csv report does not start with a byte order mark: %q", content)
+			}
+		})
+	}
+}
+
+func TestWebhook(t *testing.T) {
+	gitlab := newFakeGitlab(t, func(request graphQLRequest) string {
+		return issuesPage([]testIssue{{iid: "1", title: "Feature", timelogs: []testTimelog{{"alice", "2024-03-04T09:00:00Z", 5400}}}}, "")
+	})
+	var posted []byte
+	var authorization string
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		posted, _ = io.ReadAll(r.Body)
+		authorization = r.Header.Get("Authorization")
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer webhook.Close()
+
+	// the text report is written as usual, the webhook still gets the JSON document
+	runReport(t, gitlab, map[string]string{"WEBHOOK_URL": webhook.URL, "WEBHOOK_AUTH_HEADER": "Authorization: Bearer hook-token"})
+	if report := decodeReport(t, string(posted)); len(report.Users) != 1 || report.Users[0].Hours != 1.5 {
+		t.Errorf("posted users = %+v, want alice with 1.5h", report.Users)
+	}
+	if authorization != "Bearer hook-token" {
+		t.Errorf("Authorization = %q, want Bearer hook-token", authorization)
+	}
+}
+
+func TestSeveralMilestones(t *testing.T) {
+	gitlab := newFakeGitlab(t, func(request graphQLRequest) string {
+		return issuesPage([]testIssue{
+			{iid: "1", title: "Feature", milestone: "v1.0", timelogs: []testTimelog{{"alice", "2024-03-04T09:00:00Z", 3600}}},
+			{iid: "2", title: "Fix", milestone: "v1.1", timelogs: []testTimelog{{"alice", "2024-03-05T09:00:00Z", 5400}}},
+		}, "")
+	})
+
+	content := runReport(t, gitlab, map[string]string{"GITLAB_MILESTONE": "v1.0, v1.1"})
+	requests := gitlab.graphQLRequests()
+	if len(requests) != 1 {
+		t.Fatalf("got %d GraphQL requests, want 1", len(requests))
+	}
+	if got, want := requests[0].Variables["milestoneTitle"], []interface{}{"v1.0", "v1.1"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("milestoneTitle = %v, want %v", got, want)
+	}
+	for _, want := range []string{"for alice : 2.5h", "-- Total time spent per milestone --", "for v1.0 : 1.0h", "for v1.1 : 1.5h"} {
+		if !strings.Contains(content, want) {
+			t.Errorf("report has no %q:\n%s", want, content)
+		}
+	}
+}
+
+func TestIssueJSONReport(t *testing.T) {
+	gitlab := newFakeGitlab(t, func(request graphQLRequest) string {
+		return issuesPage([]testIssue{
+			{iid: "1", title: "Feature", timelogs: []testTimelog{{"alice", "2024-03-04T09:00:00Z", 3600}, {"bob", "2024-03-05T09:00:00Z", 5400}}},
+			{iid: "2", title: "Fix", timelogs: []testTimelog{{"alice", "2024-03-05T09:00:00Z", 1800}}},
+		}, "")
+	})
+
+	content := runReport(t, gitlab, map[string]string{"OUTPUT_FORMAT": "json", "GROUP_BY": "issue", "ALL_USERS": "true"})
+	var issues map[string]IssueSpentTime
+	if err := json.Unmarshal([]byte(content), &issues); err != nil {
+		t.Fatalf("could not decode %q: %v", content, err)
+	}
+	want := map[string]IssueSpentTime{
+		"#1": {Title: "Feature", Total: 2.5, Users: map[string]float32{"alice": 1, "bob": 1.5}},
+		"#2": {Title: "Fix", Total: 0.5, Users: map[string]float32{"alice": 0.5}},
+	}
+	if !reflect.DeepEqual(issues, want) {
+		t.Errorf("issues = %+v, want %+v", issues, want)
+	}
+}
+
+func TestTimeOffLabel(t *testing.T) {
+	gitlab := newFakeGitlab(t, func(request graphQLRequest) string {
+		return issuesPage([]testIssue{
+			{iid: "1", title: "Feature", labels: []string{"backend"}, timelogs: []testTimelog{{"alice", "2024-03-04T09:00:00Z", 20 * 3600}}},
+			{iid: "2", title: "Vacation", labels: []string{"vacation"}, timelogs: []testTimelog{{"alice", "2024-03-05T09:00:00Z", 8 * 3600}}},
+		}, "")
+	})
+
+	for _, allUsers := range []string{"", "true"} {
+		t.Run("ALL_USERS="+allUsers, func(t *testing.T) {
+			content := runReport(t, gitlab, map[string]string{"ALL_USERS": allUsers, "TIMEOFF_LABEL": "vacation", "DEFAULT_CAPACITY": "40"})
+			if !strings.Contains(content, "Capacity for alice : 20.0h / 32.0h (62%), 8.0h time off") {
+				t.Errorf("report has no capacity reduced by the time off:\n%s", content)
+			}
+			if strings.Contains(content, "Vacation") || strings.Contains(content, "28.0h") {
+				t.Errorf("time off is part of the report:\n%s", content)
+			}
+		})
+	}
+	if query := gitlab.graphQLRequests()[0].Query; !strings.Contains(query, "labels {") {
+		t.Errorf("query does not fetch the labels:\n%s", query)
+	}
+}
+
+func TestChunkByMonth(t *testing.T) {
+	feature := testIssue{iid: "1", title: "Feature", timelogs: []testTimelog{{"alice", "2024-02-20T09:00:00Z", 3600}}}
+	fix := testIssue{iid: "2", title: "Fix", timelogs: []testTimelog{{"alice", "2024-03-05T09:00:00Z", 3600}}}
+	docs := testIssue{iid: "3", title: "Docs", timelogs: []testTimelog{{"alice", "2024-04-02T09:00:00Z", 3600}}}
+	gitlab := newFakeGitlab(t, func(request graphQLRequest) string {
+		after, before := request.Variables["updatedAfter"], request.Variables["updatedBefore"]
+		switch {
+		case after == nil && before == "2024-03-01T00:00:00Z":
+			return issuesPage([]testIssue{feature}, "")
+		case after == "2024-03-01T00:00:00Z" && before == "2024-04-01T00:00:00Z":
+			// updated while the chunks were fetched, it is in two of them
+			return issuesPage([]testIssue{fix, feature}, "")
+		case after == "2024-04-01T00:00:00Z" && before == nil:
+			return issuesPage([]testIssue{docs}, "")
+		}
+		t.Errorf("unexpected chunk updatedAfter=%v updatedBefore=%v", after, before)
+		return issuesPage(nil, "")
+	})
+
+	content := runReport(t, gitlab, map[string]string{"CHUNK_BY": "month", "TIMEZONE": "UTC", "START_DATE": "2024-02-15", "END_DATE": "2024-04-10"})
+	if requests := gitlab.graphQLRequests(); len(requests) != 3 {
+		t.Errorf("got %d GraphQL requests, want one per month", len(requests))
+	}
+	if !strings.Contains(content, "for alice : 3.0h") {
+		t.Errorf("report does not count each issue once:\n%s", content)
+	}
+}
+
+func TestDryRunSendsNothing(t *testing.T) {
+	gitlab := newFakeGitlab(t, func(request graphQLRequest) string {
+		return issuesPage(nil, "")
+	})
+	// nothing listens there, any request fails the run
+	unreachable := map[string]string{"DRY_RUN": "true", "OUTPUT_FILE": "", "LOG_LEVEL": "info", "GITLAB_HOST": "http://127.0.0.1:1", "CLOCK_SKEW_THRESHOLD": "1m"}
+
+	tests := []struct {
+		name string
+		env  map[string]string
+		want []string
+	}{
+		{"project", map[string]string{}, []string{"GraphQL query:", "group/app", "(token owner)"}},
+		{"usernames", map[string]string{"USERNAMES": "alice,bob"}, []string{"username=alice"}},
+		{"member projects", map[string]string{"SCOPE": "my-projects", "GITLAB_PROJECT_PATH": ""}, []string{"(member projects)"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			env := map[string]string{}
+			for key, value := range unreachable {
+				env[key] = value
+			}
+			for key, value := range test.env {
+				env[key] = value
+			}
+			_, stderr := captureStreams(t, func() {
+				runReport(t, gitlab, env)
+			})
+			for _, want := range test.want {
+				if !strings.Contains(stderr, want) {
+					t.Errorf("stderr has no %q:\n%s", want, stderr)
+				}
+			}
+		})
+	}
+}
+
+func TestWriteTimesheet(t *testing.T) {
+	spentAt := time.Date(2024, 3, 4, 9, 30, 0, 0, time.UTC)
+	entries := []TimesheetEntry{
+		{ProjectPath: "group/app", Username: "alice", IID: "1", Title: "Feature", SpentAt: spentAt, Hours: 1.5, Name: "Alice van Dyke", Email: "alice@example.com"},
+		{ProjectPath: "group/app", Username: "bob", IID: "1", Title: "Feature", SpentAt: spentAt, Hours: -1.5},
+	}
+
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{"harvest", "Date,Client,Project,Task,Notes,Hours,First name,Last name\n" +
+			"2024-03-04,Acme,group/app,Feature,#1,1.50,Alice,van Dyke\n" +
+			"2024-03-04,Acme,group/app,Feature,#1,-1.50,bob,\n"},
+		// the correction is skipped, a negative duration can not be imported
+		{"toggl", "User,Email,Client,Project,Task,Description,Start date,Start time,Duration\n" +
+			"alice,alice@example.com,Acme,group/app,Feature,#1: Feature,2024-03-04,09:30:00,01:30:00\n"},
+	}
+	for _, test := range tests {
+		t.Run(test.format, func(t *testing.T) {
+			var output strings.Builder
+			if err := writeTimesheet(&output, test.format, "Acme", "", entries); err != nil {
+				t.Fatal(err)
+			}
+			if output.String() != test.want {
+				t.Errorf("timesheet =\n%s\nwant\n%s", output.String(), test.want)
+			}
+		})
+	}
+}
+
+func TestIssueSharesOfZeroTotal(t *testing.T) {
+	gitlab := newFakeGitlab(t, func(request graphQLRequest) string {
+		return issuesPage([]testIssue{
+			{iid: "1", title: "Feature", timelogs: []testTimelog{{"alice", "2024-03-04T09:00:00Z", 3600}}},
+			// logged on the wrong issue and corrected here
+			{iid: "2", title: "Fix", timelogs: []testTimelog{{"alice", "2024-03-05T09:00:00Z", -3600}}},
+		}, "")
+	})
+
+	content := runReport(t, gitlab, map[string]string{"GROUP_BY": "issue"})
+	if strings.Contains(content, "Inf") || strings.Contains(content, "NaN") {
+		t.Errorf("shares of a zero total are not numbers:\n%s", content)
+	}
+	if !strings.Contains(content, "1.0h (n/a, cumulative n/a) - #1: Feature") {
+		t.Errorf("report has no n/a share:\n%s", content)
+	}
+}
+
+func TestOutputFileWriteError(t *testing.T) {
+	if _, err := os.Stat("/dev/full"); err != nil {
+		t.Skip("no /dev/full to fail the writes")
+	}
+	gitlab := newFakeGitlab(t, func(request graphQLRequest) string {
+		return issuesPage([]testIssue{{iid: "1", title: "Feature", timelogs: []testTimelog{{"alice", "2024-03-04T09:00:00Z", 3600}}}}, "")
+	})
+	dir := t.TempDir()
+	emptyEnvFile := filepath.Join(dir, "empty.env")
+	if err := os.WriteFile(emptyEnvFile, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	env := map[string]string{
+		"ENV_FILES":           emptyEnvFile,
+		"GITLAB_TOKEN":        "glpat-test",
+		"GITLAB_HOST":         gitlab.URL,
+		"GITLAB_PROJECT_PATH": "group/app",
+		"START_DATE":          "2024-03-01",
+		"END_DATE":            "2024-03-31",
+		"OUTPUT_FILE":         "/dev/full",
+		"LOG_LEVEL":           "error",
+	}
+	for key, value := range env {
+		t.Setenv(key, value)
+	}
+	args := os.Args
+	os.Args = []string{args[0]}
+	defer func() { os.Args = args }()
+
+	// every write of /dev/full fails, the text report lines are written through the log package that drops the errors
+	err := run()
+	if err == nil || !strings.Contains(err.Error(), "Failed to write OUTPUT_FILE") {
+		t.Errorf("error = %v, want a failed write of OUTPUT_FILE", err)
+	}
+	if code := exitCode(err); code != exitFailure {
+		t.Errorf("exit code = %d, want %d", code, exitFailure)
+	}
+}
+
+func TestRequireAllUsersLoggedSkipsExcludedUsers(t *testing.T) {
+	gitlab := newFakeGitlab(t, func(request graphQLRequest) string {
+		return issuesPage([]testIssue{{iid: "1", title: "Feature", timelogs: []testTimelog{
+			{"alice", "2024-03-04T09:00:00Z", 3600},
+			{"bob", "2024-03-05T09:00:00Z", 3600},
+		}}}, "")
+	})
+
+	// bob logged time but is excluded, carol is expected and excluded, neither is reported as missing
+	runReport(t, gitlab, map[string]string{
+		"REQUIRE_ALL_USERS_LOGGED": "true",
+		"MEMBERS_SOURCE":           "usernames",
+		"USERNAMES":                "alice,bob,carol",
+		"EXCLUDE_USERS":            "bob,carol",
+	})
+}
+
+func TestPushgatewayURL(t *testing.T) {
+	for _, key := range []string{"PUSHGATEWAY_URL", "PUSHGATEWAY_ADDR"} {
+		t.Run(key, func(t *testing.T) {
+			gitlab := newFakeGitlab(t, func(request graphQLRequest) string {
+				return issuesPage([]testIssue{{iid: "1", title: "Feature", timelogs: []testTimelog{{"alice", "2024-03-04T09:00:00Z", 3600}}}}, "")
+			})
+			var pushes atomic.Int32
+			pushgateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if strings.HasPrefix(r.URL.Path, "/metrics/job/") {
+					pushes.Add(1)
+				}
+				w.WriteHeader(http.StatusOK)
+			}))
+			defer pushgateway.Close()
+
+			runReport(t, gitlab, map[string]string{key: pushgateway.URL})
+			if got := pushes.Load(); got != 1 {
+				t.Errorf("pushes = %d, want 1", got)
+			}
+		})
+	}
+}
+
+func TestJSONUserIDs(t *testing.T) {
+	gitlab := newFakeGitlab(t, func(request graphQLRequest) string {
+		return `{"project": {"issues": {
+			"pageInfo": {"hasNextPage": false, "endCursor": ""},
+			"nodes": [{"id": "gid://gitlab/Issue/1", "iid": "1", "title": "Feature", "timelogs": {
+				"pageInfo": {"hasNextPage": false, "endCursor": ""},
+				"nodes": [
+					{"timeSpent": 3600, "spentAt": "2024-03-04T09:00:00Z", "user": {"id": "gid://gitlab/User/7", "username": "alice"}},
+					{"timeSpent": 1800, "spentAt": "2024-03-05T09:00:00Z", "user": {"id": "gid://gitlab/User/8", "username": "bob"}}
+				]
+			}}]
+		}}}`
+	})
+
+	for _, keyBy := range []string{"username", "id"} {
+		t.Run(keyBy, func(t *testing.T) {
+			report := decodeReport(t, runReport(t, gitlab, map[string]string{"OUTPUT_FORMAT": "json", "ALL_USERS": "true", "KEY_BY": keyBy}))
+
+			// the username stays a plain username next to the stable id, whatever the key
+			wantUsers := []UserSpentTime{{Username: "alice", UserID: 7, Hours: 1}, {Username: "bob", UserID: 8, Hours: 0.5}}
+			if len(report.Users) != len(wantUsers) {
+				t.Fatalf("users = %+v, want %+v", report.Users, wantUsers)
+			}
+			for i, want := range wantUsers {
+				if got := report.Users[i]; got.Username != want.Username || got.UserID != want.UserID || got.Hours != want.Hours {
+					t.Errorf("user %d = %s %d %.1fh, want %s %d %.1fh", i, got.Username, got.UserID, got.Hours, want.Username, want.UserID, want.Hours)
+				}
+			}
+			for _, entry := range report.Entries {
+				if want := map[string]int64{"alice": 7, "bob": 8}[entry.Username]; entry.UserID != want {
+					t.Errorf("user_id of the %s entry = %d, want %d", entry.Username, entry.UserID, want)
+				}
+			}
+		})
+	}
+}
+
+func TestMovedToFetchedIssue(t *testing.T) {
+	// issues keyed by id, each moved to the id it maps to ("" when it was never moved)
+	fetched := func(moves map[string]string) map[string]Issue {
+		issuesByID := make(map[string]Issue)
+		for id, movedTo := range moves {
+			issue := Issue{ID: id}
+			if movedTo != "" {
+				issue.MovedTo = &struct {
+					ID string `json:"id"`
+				}{ID: movedTo}
+			}
+			issuesByID[id] = issue
+		}
+		return issuesByID
+	}
+
+	tests := []struct {
+		name  string
+		moves map[string]string
+		// id = whether its time is counted on the issue it was moved to
+		want map[string]bool
+	}{
+		{"not moved", map[string]string{"1": ""}, map[string]bool{"1": false}},
+		{"plain chain", map[string]string{"1": "2", "2": "3", "3": ""}, map[string]bool{"1": true, "2": true, "3": false}},
+		{"chain leaving the fetched issues", map[string]string{"1": "2", "2": "9"}, map[string]bool{"1": true, "2": false}},
+		{"moved out of the fetched issues", map[string]string{"1": "9"}, map[string]bool{"1": false}},
+		{"cycle", map[string]string{"1": "2", "2": "1"}, map[string]bool{"1": false, "2": true}},
+		{"chain into a cycle", map[string]string{"1": "2", "2": "3", "3": "2"}, map[string]bool{"1": true, "2": false, "3": true}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			issuesByID := fetched(test.moves)
+			for id, want := range test.want {
+				done := make(chan bool)
+				go func() { done <- movedToFetchedIssue(issuesByID[id], issuesByID) }()
+				select {
+				case got := <-done:
+					if got != want {
+						t.Errorf("movedToFetchedIssue(%s) = %v, want %v", id, got, want)
+					}
+				case <-time.After(time.Second):
+					t.Fatalf("movedToFetchedIssue(%s) did not return, the chain of moves loops", id)
+				}
+			}
+		})
+	}
+}
+
+func TestParseGitlabDuration(t *testing.T) {
+	tests := []struct {
+		duration string
+		want     int
+		wantOK   bool
+	}{
+		{"30m", 30 * 60, true},
+		{"1h 30m", 90 * 60, true},
+		{"1.5h", 90 * 60, true},
+		{"2d", 2 * 8 * 3600, true},
+		{"1w", 5 * 8 * 3600, true},
+		{"1mo", 4 * 5 * 8 * 3600, true},
+		{"1mo 1w 1d 1h 1m 1s", (20+5+1)*8*3600 + 3600 + 60 + 1, true},
+		{" 3h ", 3 * 3600, true},
+		{"", 0, false},
+		{"0h", 0, false},
+		{"3 hours", 0, false},
+		{"1h tomorrow", 0, false},
+	}
+	for _, test := range tests {
+		t.Run(test.duration, func(t *testing.T) {
+			got, ok := parseGitlabDuration(test.duration)
+			if got != test.want || ok != test.wantOK {
+				t.Errorf("parseGitlabDuration(%q) = %d, %v, want %d, %v", test.duration, got, ok, test.want, test.wantOK)
+			}
+		})
+	}
+}
+
+func TestNotesTime(t *testing.T) {
+	createdAt := time.Date(2024, 3, 4, 10, 0, 0, 0, time.UTC)
+	system := func(body string) *gitlab.Note {
+		return &gitlab.Note{Body: body, System: true, CreatedAt: &createdAt}
+	}
+	comment := func(body string) *gitlab.Note {
+		return &gitlab.Note{Body: body, CreatedAt: &createdAt}
+	}
+
+	tests := []struct {
+		name         string
+		notes        []*gitlab.Note
+		wantPerDay   map[string]int
+		wantEstimate int
+	}{
+		{"added", []*gitlab.Note{system("added 1h 30m of time spent")}, map[string]int{"2024-03-04": 90 * 60}, 0},
+		{"added at a date", []*gitlab.Note{system("added 1d of time spent at 2024-03-01")}, map[string]int{"2024-03-01": 8 * 3600}, 0},
+		{"subtracted", []*gitlab.Note{system("added 2h of time spent"), system("subtracted 30m of time spent")}, map[string]int{"2024-03-04": 90 * 60}, 0},
+		{"removed time spent", []*gitlab.Note{system("added 1w of time spent at 2024-03-01"), system("removed time spent"), system("added 1h of time spent")}, map[string]int{"2024-03-04": 3600}, 0},
+		{"estimate", []*gitlab.Note{system("changed time estimate to 1mo"), system("changed time estimate to 2w")}, map[string]int{}, 2 * 5 * 8 * 3600},
+		{"removed time estimate", []*gitlab.Note{system("changed time estimate to 3d"), system("removed time estimate")}, map[string]int{}, 0},
+		{"quick actions", []*gitlab.Note{comment("done\n/spend 1h 2024-03-02\n/spend -15m\n/estimate 2d")}, map[string]int{"2024-03-02": 3600, "2024-03-04": -15 * 60}, 2 * 8 * 3600},
+		{"unparsable duration", []*gitlab.Note{system("added soon of time spent"), comment("/spend a while")}, map[string]int{}, 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fromNotes := notesTime{spentPerDay: make(map[string]int)}
+			for _, note := range test.notes {
+				fromNotes.add(note)
+			}
+			if !reflect.DeepEqual(fromNotes.spentPerDay, test.wantPerDay) {
+				t.Errorf("spent per day = %v, want %v", fromNotes.spentPerDay, test.wantPerDay)
+			}
+			if fromNotes.estimate != test.wantEstimate {
+				t.Errorf("estimate = %d, want %d", fromNotes.estimate, test.wantEstimate)
+			}
+		})
+	}
+}
+
+func TestRetryTransport(t *testing.T) {
+	tests := []struct {
+		name         string
+		statuses     []int
+		retries      int
+		wantAttempts int
+		wantStatus   int
+	}{
+		{"retried until it succeeds", []int{http.StatusBadGateway, http.StatusOK}, 3, 2, http.StatusOK},
+		{"rate limited", []int{http.StatusTooManyRequests, http.StatusOK}, 3, 2, http.StatusOK},
+		{"not retryable", []int{http.StatusUnauthorized}, 3, 1, http.StatusUnauthorized},
+		{"retries exhausted", []int{http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway}, 2, 3, http.StatusBadGateway},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var mu sync.Mutex
+			var bodies []string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				mu.Lock()
+				attempt := len(bodies)
+				bodies = append(bodies, string(body))
+				mu.Unlock()
+				w.WriteHeader(test.statuses[min(attempt, len(test.statuses)-1)])
+			}))
+			defer server.Close()
+
+			client := &http.Client{Transport: &retryTransport{retries: test.retries, baseDelay: time.Millisecond, next: http.DefaultTransport}}
+			res, err := client.Post(server.URL, "application/json", strings.NewReader(`{"query": "{ currentUser { id } }"}`))
+			if err != nil {
+				t.Fatal(err)
+			}
+			res.Body.Close()
+
+			if res.StatusCode != test.wantStatus {
+				t.Errorf("status = %d, want %d", res.StatusCode, test.wantStatus)
+			}
+			if len(bodies) != test.wantAttempts {
+				t.Fatalf("attempts = %d, want %d", len(bodies), test.wantAttempts)
+			}
+			// every attempt replays the whole body from GetBody
+			for i, body := range bodies {
+				if body != bodies[0] || body == "" {
+					t.Errorf("body of attempt %d = %q, want %q", i+1, body, bodies[0])
+				}
+			}
+		})
+	}
+}
+
+func TestRateLimitTransport(t *testing.T) {
+	tests := []struct {
+		name      string
+		remaining string
+		wantPause bool
+	}{
+		{"under the threshold", "0", true},
+		{"above the threshold", "100", false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// the reset is a unix timestamp in seconds, 2s ahead pauses the next request for more than 1s
+			reset := time.Now().Add(2 * time.Second).Unix()
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("RateLimit-Remaining", test.remaining)
+				w.Header().Set("RateLimit-Reset", strconv.FormatInt(reset, 10))
+			}))
+			defer server.Close()
+
+			client := &http.Client{Transport: &rateLimitTransport{next: http.DefaultTransport}}
+			var requestTimes []time.Time
+			for i := 0; i < 2; i++ {
+				res, err := client.Get(server.URL)
+				if err != nil {
+					t.Fatal(err)
+				}
+				res.Body.Close()
+				requestTimes = append(requestTimes, time.Now())
+			}
+
+			paused := requestTimes[1].Sub(requestTimes[0]) > time.Second
+			if paused != test.wantPause {
+				t.Errorf("second request after %v, want a pause until the reset: %v", requestTimes[1].Sub(requestTimes[0]), test.wantPause)
+			}
+		})
+	}
+}
+
+func TestAgingReportReferences(t *testing.T) {
+	gitlab := newFakeGitlab(t, func(request graphQLRequest) string {
+		if request.Variables["fullPath"] == "group/api" {
+			return issuesPage(nil, "")
+		}
+		return issuesPage([]testIssue{{iid: "4", title: "Barely started", timeEstimate: 10 * 3600, totalTimeSpent: 1800, timelogs: []testTimelog{{"alice", "2024-03-04T09:00:00Z", 1800}}}}, "")
+	})
+
+	// with several projects the reference names the project, like the detail lines
+	content := runReport(t, gitlab, map[string]string{"AGING_REPORT": "true", "GITLAB_PROJECT_PATH": "group/app,group/api"})
+	want := "0.5h / 10.0h estimated (5%) - group/app#4: Barely started " + gitlab.URL + "/group/app/-/issues/4"
+	if !strings.Contains(content, want) {
+		t.Errorf("report has no %q line:\n%s", want, content)
+	}
+}
+
+func TestCSVReportKind(t *testing.T) {
+	spentAt := time.Date(2024, 3, 4, 9, 0, 0, 0, time.UTC)
+	entries := []TimesheetEntry{
+		{ProjectPath: "group/app", IID: "1", Title: "Feature", Username: "alice", SpentAt: spentAt, Hours: 1},
+		{ProjectPath: "group/app", MergeRequest: true, IID: "1", Title: "Review", Username: "alice", SpentAt: spentAt, Hours: 0.5},
+	}
+
+	var b strings.Builder
+	if err := writeCSVReport(&b, entries, nil, nil, ReportOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	// the issue and the merge request share iid 1, only the kind column tells them apart
+	for _, want := range []string{
+		"project,issue_iid,issue_title,username,hours,spent_at,category,kind\n",
+		"group/app,1,Feature,alice,1.00,2024-03-04,dev,issue\n",
+		"group/app,1,Review,alice,0.50,2024-03-04,dev,merge_request\n",
+	} {
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("csv report has no %q:\n%s", want, b.String())
+		}
+	}
+}
+
+func TestSparklineOnTotalLines(t *testing.T) {
+	gitlab := newFakeGitlab(t, func(request graphQLRequest) string {
+		return issuesPage([]testIssue{{iid: "1", title: "Feature", timelogs: []testTimelog{
+			{"alice", "2024-03-02T09:00:00Z", 7200},
+			{"alice", "2024-03-04T09:00:00Z", 3600},
+		}}}, "")
+	})
+
+	tests := []struct {
+		name string
+		env  map[string]string
+		want string
+	}{
+		{"user", map[string]string{}, "Total spent time from 2024-03-01 to 2024-03-04 for alice : 3.0h ▁█▁▄\n"},
+		{"all users", map[string]string{"ALL_USERS": "true"}, "from 2024-03-01 to 2024-03-04 for alice : 3.0h ▁█▁▄\n"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			env := map[string]string{"SPARKLINE": "true", "END_DATE": "2024-03-04"}
+			for key, value := range test.env {
+				env[key] = value
+			}
+			content := runReport(t, gitlab, env)
+			if !strings.Contains(content, test.want) {
+				t.Errorf("report has no %q:\n%s", test.want, content)
+			}
+			if strings.Contains(content, "Daily activity") {
+				t.Errorf("report still has a separate sparkline section:\n%s", content)
+			}
+		})
+	}
+}
+
+func TestIssueStateFiltersMergeRequests(t *testing.T) {
+	mergeRequest := func(iid string, state string) string {
+		return fmt.Sprintf(`{"id": "gid://gitlab/MergeRequest/%[1]s", "iid": "%[1]s", "title": "MR %[1]s", "state": %[2]q, "timelogs": {
+			"pageInfo": {"hasNextPage": false, "endCursor": ""},
+			"nodes": [{"timeSpent": 3600, "spentAt": "2024-03-04T09:00:00Z", "user": {"id": "gid://gitlab/User/alice", "username": "alice"}}]
+		}}`, iid, state)
+	}
+	tests := []struct {
+		name      string
+		state     string
+		wantState interface{}
+		wantMRs   []string
+	}{
+		{"opened", "opened", "opened", []string{"1"}},
+		{"closed keeps the merged ones", "closed", nil, []string{"2", "3"}},
+		{"all", "all", nil, []string{"1", "2", "3"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			gitlab := newFakeGitlab(t, func(request graphQLRequest) string {
+				if !strings.Contains(request.Query, "mergeRequests(") {
+					return issuesPage(nil, "")
+				}
+				nodes := mergeRequest("1", "opened") + "," + mergeRequest("2", "merged") + "," + mergeRequest("3", "closed")
+				// Gitlab returns every state without the argument
+				if request.Variables["state"] == "opened" {
+					nodes = mergeRequest("1", "opened")
+				}
+				return `{"project": {"mergeRequests": {"pageInfo": {"hasNextPage": false, "endCursor": ""}, "nodes": [` + nodes + `]}}}`
+			})
+
+			report := decodeReport(t, runReport(t, gitlab, map[string]string{"OUTPUT_FORMAT": "json", "WITH_MERGE_REQUESTS": "true", "ISSUE_STATE": test.state}))
+			var iids []string
+			for _, entry := range report.Entries {
+				iids = append(iids, entry.IID)
+			}
+			if !reflect.DeepEqual(iids, test.wantMRs) {
+				t.Errorf("merge requests reported = %v, want %v", iids, test.wantMRs)
+			}
+			for _, request := range gitlab.graphQLRequests() {
+				if strings.Contains(request.Query, "mergeRequests(") && request.Variables["state"] != test.wantState {
+					t.Errorf("state of the merge requests query = %v, want %v", request.Variables["state"], test.wantState)
+				}
+			}
+		})
+	}
+}
+
+func TestAssigneeQueryVariable(t *testing.T) {
+	gitlab := newFakeGitlab(t, func(request graphQLRequest) string {
+		if strings.Contains(request.Query, "mergeRequests(") {
+			return `{"project": {"mergeRequests": {"pageInfo": {"hasNextPage": false, "endCursor": ""}, "nodes": []}}}`
+		}
+		return issuesPage([]testIssue{{iid: "1", title: "Feature", timelogs: []testTimelog{{"bob", "2024-03-04T09:00:00Z", 3600}}}}, "")
+	})
+
+	// Gitlab filters the issues and merge requests, the assignees are not fetched to filter them again
+	runReport(t, gitlab, map[string]string{"GITLAB_ASSIGNEE": "alice", "WITH_MERGE_REQUESTS": "true"})
+	for _, request := range gitlab.graphQLRequests() {
+		if strings.Contains(request.Query, "assignees {") {
+			t.Errorf("query fetches the assignees:\n%s", request.Query)
+		}
+		if strings.Contains(request.Query, "mergeRequests(") {
+			if request.Variables["assigneeUsername"] != "alice" {
+				t.Errorf("assigneeUsername of the merge requests query = %v, want alice", request.Variables["assigneeUsername"])
+			}
+		} else if !reflect.DeepEqual(request.Variables["assigneeUsernames"], []interface{}{"alice"}) {
+			t.Errorf("assigneeUsernames of the issues query = %v, want [alice]", request.Variables["assigneeUsernames"])
+		}
+	}
+}
+
+func TestRunTagInEveryFormat(t *testing.T) {
+	gitlab := newFakeGitlab(t, func(request graphQLRequest) string {
+		return issuesPage([]testIssue{{iid: "1", title: "Feature", timelogs: []testTimelog{{"alice", "2024-03-04T09:00:00Z", 3600}}}}, "")
+	})
+
+	tests := []struct {
+		name string
+		env  map[string]string
+		want []string
+	}{
+		{"markdown", map[string]string{"OUTPUT_FORMAT": "markdown"}, []string{"Run tag: sprint-42\n"}},
+		{"harvest", map[string]string{"OUTPUT_FORMAT": "harvest"}, []string{",#1 [sprint-42],1.00,"}},
+		{"toggl", map[string]string{"OUTPUT_FORMAT": "toggl"}, []string{",Duration,Tags\n", ",01:00:00,sprint-42\n"}},
+		{"date json", map[string]string{"OUTPUT_FORMAT": "json", "GROUP_BY": "date"}, []string{`"run_tag":"sprint-42"`}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.env["RUN_TAG"] = "sprint-42"
+			content := runReport(t, gitlab, test.env)
+			for _, want := range test.want {
+				if !strings.Contains(content, want) {
+					t.Errorf("report has no %q:\n%s", want, content)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// A pipe in a title would end the table cell
+func escapeMarkdownCell(s string) string {
+	return strings.ReplaceAll(s, "|", "\\|")
+}
+
+// Date range and per user totals table, the part of the markdown report also posted to Slack
+func markdownSummary(report AllUsersReport, reportOptions ReportOptions) string {
+	hours := func(h float32) string {
+		return fmt.Sprintf("%.1f", roundHours(h, reportOptions.RoundingMode))
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "## Spent time from %s to %s\n\n", report.Start.Format("2006-01-02"), report.End.Format("2006-01-02"))
+	if reportOptions.RunTag != "" {
+		fmt.Fprintf(&b, "Run tag: %s\n\n", reportOptions.RunTag)
+	}
+	b.WriteString("| User | Dev h | Non-dev h | Total |\n")
+	b.WriteString("| --- | ---: | ---: | ---: |\n")
+	var totalDev, totalNonDev, total float32
+	for _, user := range report.sortedUsers(report.TotalHours, reportOptions.SortBy) {
+		fmt.Fprintf(&b, "| %s | %s | %s | %s |\n", escapeMarkdownCell(report.userLabel(user, reportOptions.KeyBy)), hours(report.DevHours[user]), hours(report.NonDevHours[user]), hours(report.TotalHours[user]))
+		totalDev += report.DevHours[user]
+		totalNonDev += report.NonDevHours[user]
+		total += report.TotalHours[user]
+	}
+	fmt.Fprintf(&b, "| **Total** | **%s** | **%s** | **%s** |\n", hours(totalDev), hours(totalNonDev), hours(total))
+
+	return b.String()
+}
+
+// GitLab flavored Markdown meant to be pasted in a comment, per user totals in a table and issues folded below it
+func writeMarkdownReport(w io.Writer, report AllUsersReport, reportOptions ReportOptions) error {
+	hours := func(h float32) string {
+		return fmt.Sprintf("%.1f", roundHours(h, reportOptions.RoundingMode))
+	}
+
+	var b strings.Builder
+	b.WriteString(markdownSummary(report, reportOptions))
+
+	type issueTime struct {
+		ProjectPath  string
+		MergeRequest bool
+		IID          string
+		Title        string
+		Hours        float32
+	}
+	var issues []*issueTime
+	issueIndex := make(map[string]*issueTime)
+	for _, entry := range report.Entries {
+		key := entry.ProjectPath + referencePrefix(entry.MergeRequest) + entry.IID
+		if _, ok := issueIndex[key]; !ok {
+			issueIndex[key] = &issueTime{ProjectPath: entry.ProjectPath, MergeRequest: entry.MergeRequest, IID: entry.IID, Title: entry.Title}
+			issues = append(issues, issueIndex[key])
+		}
+		issueIndex[key].Hours += entry.Hours
+	}
+	sort.SliceStable(issues, func(i, j int) bool {
+		return issues[i].Hours > issues[j].Hours
+	})
+
+	// a blank line is needed around the table for GitLab to render it inside the details block
+	b.WriteString("\n<details>\n<summary>Time per issue</summary>\n\n")
+	b.WriteString("| Issue | Title | Hours |\n")
+	b.WriteString("| --- | --- | ---: |\n")
+	for _, issue := range issues {
+		if isZeroHours(issue.Hours) && !reportOptions.ShowZero {
+			continue
+		}
+		ref := issueRef(issue.ProjectPath, issue.IID, issue.MergeRequest, reportOptions)
+		fmt.Fprintf(&b, "| [%s](%s) | %s | %s |\n", ref, issueURL(reportOptions.GitlabHost, issue.ProjectPath, issue.IID, issue.MergeRequest), escapeMarkdownCell(issue.Title), hours(issue.Hours))
+	}
+	b.WriteString("\n</details>\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
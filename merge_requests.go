@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/machinebox/graphql"
+)
+
+type MergeRequestTimelogData struct {
+	Project struct {
+		MergeRequests struct {
+			PageInfo PageInfo `json:"pageInfo"`
+			// merge requests share the fields of issues that matter to the reports
+			Nodes []Issue `json:"nodes"`
+		} `json:"mergeRequests"`
+	} `json:"project"`
+	// number of GraphQL requests needed to fetch every page
+	PagesFetched int `json:"-"`
+}
+
+// Time logged on merge requests, typically for code review, is not part of the issue timelogs
+func getMergeRequestTimelogs(projectId string, apiToken string, queryOptions QueryOptions, client *graphql.Client, ctx context.Context) (*MergeRequestTimelogData, error) {
+	var data MergeRequestTimelogData
+	after := ""
+	mergeRequestPages := 0
+	for {
+		page, err := getMergeRequestsPage(projectId, apiToken, queryOptions, after, client, ctx)
+		if err != nil {
+			return nil, err
+		}
+		data.PagesFetched++
+		mergeRequestPages++
+
+		for _, mergeRequest := range page.Project.MergeRequests.Nodes {
+			// a done merge request is usually merged, ISSUE_STATE=closed keeps both
+			if queryOptions.State == "closed" && mergeRequest.State != "closed" && mergeRequest.State != "merged" {
+				continue
+			}
+			mergeRequest.ProjectPath = projectId
+			mergeRequest.MergeRequest = true
+			if mergeRequest.Timelogs.PageInfo.HasNextPage {
+				pages, err := getRemainingTimelogs(projectId, apiToken, queryOptions, "mergeRequest", &mergeRequest, client, ctx)
+				if err != nil {
+					return nil, err
+				}
+				data.PagesFetched += pages
+			}
+			data.Project.MergeRequests.Nodes = append(data.Project.MergeRequests.Nodes, mergeRequest)
+		}
+
+		logger.Info("Fetched page", "project", projectId, "page", mergeRequestPages, "merge_requests", len(data.Project.MergeRequests.Nodes))
+		if !page.Project.MergeRequests.PageInfo.HasNextPage {
+			return &data, nil
+		}
+		after = page.Project.MergeRequests.PageInfo.EndCursor
+	}
+}
+
+func getMergeRequestsPage(projectId string, apiToken string, queryOptions QueryOptions, after string, client *graphql.Client, ctx context.Context) (*MergeRequestTimelogData, error) {
+	// Same label filters as issues, merge requests name the argument labels
+	queryVars := "$fullPath: ID!, $first: Int, $after: String"
+	mergeRequestFilters := []string{"first: $first", "after: $after"}
+	vars := map[string]interface{}{"fullPath": projectId, "first": pageSize}
+	if after != "" {
+		vars["after"] = after
+	}
+	// merge requests take a single title, several milestones are rejected with WITH_MERGE_REQUESTS
+	if len(queryOptions.Milestones) > 0 {
+		queryVars += ", $milestoneTitle: String"
+		mergeRequestFilters = append(mergeRequestFilters, "milestoneTitle: $milestoneTitle")
+		vars["milestoneTitle"] = queryOptions.Milestones[0]
+	}
+	// the state argument takes a single state, closed and merged are filtered once fetched
+	if queryOptions.State == "opened" {
+		queryVars += ", $state: MergeRequestState"
+		mergeRequestFilters = append(mergeRequestFilters, "state: $state")
+		vars["state"] = queryOptions.State
+	}
+	if queryOptions.Assignee != "" {
+		queryVars += ", $assigneeUsername: String"
+		mergeRequestFilters = append(mergeRequestFilters, "assigneeUsername: $assigneeUsername")
+		vars["assigneeUsername"] = queryOptions.Assignee
+	}
+	if len(queryOptions.Labels) > 0 {
+		queryVars += ", $labels: [String!]"
+		mergeRequestFilters = append(mergeRequestFilters, "labels: $labels")
+		vars["labels"] = queryOptions.Labels
+	}
+	if len(queryOptions.ExcludeLabels) > 0 {
+		queryVars += ", $notLabels: [String!]"
+		mergeRequestFilters = append(mergeRequestFilters, "not: { labels: $notLabels }")
+		vars["notLabels"] = queryOptions.ExcludeLabels
+	}
+
+	var mergeRequestFields string
+	if queryOptions.WithMilestone {
+		mergeRequestFields += `
+						milestone {
+							title
+						}`
+	}
+	if queryOptions.WithLabels {
+		mergeRequestFields += labelsField
+	}
+
+	req := graphql.NewRequest(fmt.Sprintf(`
+		query %s(%s) {
+			project(fullPath: $fullPath) {
+				mergeRequests(%s) {
+					pageInfo {
+						hasNextPage
+						endCursor
+					}
+					nodes {
+						id
+						iid
+						title
+						updatedAt
+						state
+						timeEstimate
+						totalTimeSpent%s
+						timelogs(first: %d) {
+							pageInfo {
+								hasNextPage
+								endCursor
+							}
+							nodes {%s
+							}
+						}
+					}
+				}
+			}
+		}
+		`, queryOptions.OperationName, queryVars, strings.Join(mergeRequestFilters, ", "), mergeRequestFields, pageSize, timelogNodeFields(queryOptions)))
+
+	for key, value := range vars {
+		req.Var(key, value)
+	}
+	if apiToken != "" {
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", apiToken))
+	}
+
+	var data MergeRequestTimelogData
+	if err := client.Run(ctx, req, &data); err != nil {
+		return nil, err
+	}
+
+	return &data, nil
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/push"
+)
+
+// Push per-user hours to a Prometheus Pushgateway, errors are only logged so the report is never lost
+func pushMetrics(pushgatewayAddr string, jobName string, hoursPerCategory map[string]map[string]float32) {
+	loggedHours := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "gitlab_logged_hours",
+		Help: "Hours logged on gitlab issues within the reported window.",
+	}, []string{"username", "category"})
+
+	for category, hoursPerUser := range hoursPerCategory {
+		for username, hours := range hoursPerUser {
+			loggedHours.WithLabelValues(username, category).Set(float64(hours))
+		}
+	}
+
+	// graphs can tell a run that logged no hours from a run that did not happen
+	runTimestamp := prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "gitlab_report_run_timestamp",
+		Help: "Unix time of the last report run.",
+	})
+	runTimestamp.Set(float64(time.Now().Unix()))
+
+	if err := push.New(pushgatewayAddr, jobName).Collector(loggedHours).Collector(runTimestamp).Push(); err != nil {
+		logger.Warn("Failed to push metrics", "pushgateway", pushgatewayAddr, "error", err)
+		return
+	}
+	logger.Info("Pushed metrics", "pushgateway", pushgatewayAddr)
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+
+	"golang.org/x/oauth2"
+)
+
+// Hourly OAuth2 access tokens of a Gitlab application, refreshed when they expire and shared by the REST and GraphQL clients
+func newOAuthTokenSource(gitlabHost string, clientID string, clientSecret string, refreshToken string, refreshTokenFile string, transport http.RoundTripper) oauth2.TokenSource {
+	config := &oauth2.Config{
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		Endpoint: oauth2.Endpoint{
+			AuthURL:  gitlabHost + "/oauth/authorize",
+			TokenURL: gitlabHost + "/oauth/token",
+		},
+	}
+	// the token endpoint is reached through the same proxy and CA as the API
+	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, &http.Client{Transport: transport})
+
+	return &refreshTokenSaver{next: config.TokenSource(ctx, &oauth2.Token{RefreshToken: refreshToken}), path: refreshTokenFile, refreshToken: refreshToken}
+}
+
+// Gitlab revokes a refresh token once used, the new one is written back so the next run can refresh again
+type refreshTokenSaver struct {
+	next         oauth2.TokenSource
+	path         string
+	mu           sync.Mutex
+	refreshToken string
+}
+
+func (s *refreshTokenSaver) Token() (*oauth2.Token, error) {
+	token, err := s.next.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if token.RefreshToken == "" || token.RefreshToken == s.refreshToken {
+		return token, nil
+	}
+	s.refreshToken = token.RefreshToken
+	if s.path == "" {
+		logger.Warn("Gitlab issued a new OAuth refresh token, set OAUTH_REFRESH_TOKEN_FILE to keep it for the next run")
+		return token, nil
+	}
+	if err := os.WriteFile(s.path, []byte(token.RefreshToken+"\n"), 0o600); err != nil {
+		logger.Warn("Could not save the new OAuth refresh token", "file", s.path, "error", err)
+	}
+
+	return token, nil
+}
+
+// OAUTH_REFRESH_TOKEN_FILE holds the refresh token written back by the previous run, and wins over OAUTH_REFRESH_TOKEN
+func readRefreshToken(refreshToken string, refreshTokenFile string) (string, error) {
+	if refreshTokenFile == "" {
+		return refreshToken, nil
+	}
+	content, err := os.ReadFile(refreshTokenFile)
+	if os.IsNotExist(err) && refreshToken != "" {
+		return refreshToken, nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(string(content)), nil
+}
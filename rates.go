@@ -0,0 +1,90 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Hourly rate per username, read from a .csv file of username,rate rows or from a JSON object
+func loadRates(path string) (map[string]float64, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	rates := make(map[string]float64)
+	if !strings.HasSuffix(strings.ToLower(path), ".csv") {
+		if err := json.Unmarshal(content, &rates); err != nil {
+			return nil, fmt.Errorf("could not parse %s: %w", path, err)
+		}
+		return rates, nil
+	}
+
+	records, err := csv.NewReader(strings.NewReader(string(content))).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("could not parse %s: %w", path, err)
+	}
+	for i, record := range records {
+		if len(record) != 2 {
+			return nil, fmt.Errorf("could not parse %s: line %d must be username,rate", path, i+1)
+		}
+		rate, err := strconv.ParseFloat(strings.TrimSpace(record[1]), 64)
+		if err != nil {
+			// an optional header line
+			if i == 0 {
+				continue
+			}
+			return nil, fmt.Errorf("could not parse %s: line %d has no valid rate", path, i+1)
+		}
+		rates[strings.TrimSpace(record[0])] = rate
+	}
+
+	return rates, nil
+}
+
+var currencyCode = regexp.MustCompile(`^[A-Z]{3}$`)
+
+// ISO codes such as EUR follow the amount, symbols such as € precede it
+func formatAmount(amount float64, currency string) string {
+	if currencyCode.MatchString(currency) {
+		return fmt.Sprintf("%.2f %s", amount, currency)
+	}
+
+	return fmt.Sprintf("%s%.2f", currency, amount)
+}
+
+// Billable amount per user, dev hours times the user's rate, users without a rate are listed but never billed
+func getCostReport(start time.Time, end time.Time, trackingIssues []string, trackingPattern *regexp.Regexp, timelogData *TimelogData, reportOptions ReportOptions, rates map[string]float64, currency string, stats *RunStats) {
+	report := getAllUsersSpentTime(start, end, trackingIssues, trackingPattern, timelogData, reportOptions, stats)
+
+	log.Println(sectionHeader(translate(reportOptions.Language, "-- Billable dev time --")))
+	var total float64
+	var missingUsernames []string
+	for _, user := range report.sortedUsers(report.DevHours, reportOptions.SortBy) {
+		// amounts are computed on the printed hours so the lines add up
+		hours := roundHours(report.DevHours[user], reportOptions.RoundingMode)
+		rate, ok := rates[report.Usernames[user]]
+		if !ok {
+			missingUsernames = append(missingUsernames, report.Usernames[user])
+			log.Printf("%s : %.1fh, NO RATE in RATES_FILE, not billed", report.userLabel(user, reportOptions.KeyBy), hours)
+			continue
+		}
+		amount := float64(hours) * rate
+		total += amount
+		log.Printf("%s : %.1fh x %s = %s", report.userLabel(user, reportOptions.KeyBy), hours, formatAmount(rate, currency), formatAmount(amount, currency))
+	}
+	log.Printf("Total : %s", formatAmount(total, currency))
+
+	if len(missingUsernames) > 0 {
+		sort.Strings(missingUsernames)
+		logger.Warn("Users without a rate are not billed", "usernames", strings.Join(missingUsernames, ", "))
+	}
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"io"
+	"regexp"
+	"strings"
+)
+
+var bearerPattern = regexp.MustCompile(`(?i)(bearer\s+)[^\s"',\]]+`)
+
+// Scrub known secrets and any Bearer value from a string before it is logged
+func redact(s string, secrets []string) string {
+	for _, secret := range secrets {
+		if secret != "" {
+			s = strings.ReplaceAll(s, secret, "[REDACTED]")
+		}
+	}
+
+	return bearerPattern.ReplaceAllString(s, "${1}[REDACTED]")
+}
+
+// Writer redacting everything written through it, used as the log output
+type redactingWriter struct {
+	w       io.Writer
+	secrets []string
+}
+
+func (r *redactingWriter) Write(p []byte) (int, error) {
+	if _, err := io.WriteString(r.w, redact(string(p), r.secrets)); err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// Reports served over HTTP by SERVE_ADDR, every option but the user and the window comes from the env
+type reportServer struct {
+	// fetch every reported project, called with the context of the request missing the cache
+	fetch          func(ctx context.Context) (*TimelogData, error)
+	cacheTTL       time.Duration
+	requestTimeout time.Duration
+	// window of a request without days
+	defaultDays     int
+	trackingIssues  []string
+	trackingPattern *regexp.Regexp
+	reportOptions   ReportOptions
+
+	// single slot semaphore guarding the cache, held while fetching so concurrent misses fetch once
+	lock      chan struct{}
+	cached    *TimelogData
+	fetchedAt time.Time
+}
+
+// Cached timelogs when fresh enough, waiting for the lock gives up with the request context
+func (s *reportServer) timelogData(ctx context.Context) (*TimelogData, error) {
+	select {
+	case s.lock <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	defer func() { <-s.lock }()
+
+	if s.cached != nil && time.Since(s.fetchedAt) < s.cacheTTL {
+		return s.cached, nil
+	}
+	timelogData, err := s.fetch(ctx)
+	if err != nil {
+		return nil, err
+	}
+	s.cached, s.fetchedAt = timelogData, time.Now()
+
+	return timelogData, nil
+}
+
+// GET /report?user=&days=, the single user report when user is set and the all users report otherwise
+func (s *reportServer) handleReport(w http.ResponseWriter, r *http.Request) {
+	days := s.defaultDays
+	if daysParam := r.URL.Query().Get("days"); daysParam != "" {
+		var err error
+		days, err = strconv.Atoi(daysParam)
+		if err != nil || days < 0 {
+			http.Error(w, "days must be a positive integer", http.StatusBadRequest)
+			return
+		}
+	}
+
+	ctx := r.Context()
+	if s.requestTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.requestTimeout)
+		defer cancel()
+	}
+
+	// cached issues are shared between requests, the report functions only read them
+	timelogData, err := s.timelogData(ctx)
+	if err != nil {
+		logger.Error("Failed to fetch timelogs", "error", err)
+		status := http.StatusBadGateway
+		if ctx.Err() == context.DeadlineExceeded {
+			status = http.StatusGatewayTimeout
+		}
+		http.Error(w, "could not fetch timelogs", status)
+		return
+	}
+
+	today := localDay(time.Now(), s.reportOptions.FilterLocation)
+	start, end := today.AddDate(0, 0, -days), today
+	var report SpentTimeReport
+	if username := r.URL.Query().Get("user"); username != "" {
+		report = userSpentTimeReport(getUserSpentTime(start, end, username, timelogData, s.reportOptions, newRunStats()), s.reportOptions)
+	} else {
+		report = allUsersSpentTimeReport(getAllUsersSpentTime(start, end, s.trackingIssues, s.trackingPattern, timelogData, s.reportOptions, newRunStats()), s.reportOptions)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := writeSpentTimeReport(w, report); err != nil {
+		logger.Warn("Failed to write report", "error", err)
+	}
+}
+
+func (s *reportServer) listenAndServe(addr string) error {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/report", s.handleReport)
+	server := &http.Server{Addr: addr, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+
+	logger.Info("Serving reports", "addr", addr)
+	return server.ListenAndServe()
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// A slow Slack must not hold a nightly run for long
+const slackTimeout = 10 * time.Second
+
+// Post the markdown summary to a Slack incoming webhook, errors are only logged so the report is never lost
+func postSlackSummary(webhookURL string, report AllUsersReport, reportOptions ReportOptions) {
+	payload, err := json.Marshal(map[string]string{"text": markdownSummary(report, reportOptions)})
+	if err != nil {
+		logger.Error("Failed to post the summary to Slack", "error", err)
+		return
+	}
+
+	// the webhook URL is a secret, it is never logged
+	client := &http.Client{Timeout: slackTimeout}
+	resp, err := client.Post(webhookURL, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		logger.Error("Failed to post the summary to Slack", "error", redact(err.Error(), []string{webhookURL}))
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		logger.Error("Failed to post the summary to Slack", "error", fmt.Sprintf("unexpected status %s", resp.Status))
+		return
+	}
+	logger.Info("Posted the summary to Slack")
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	gitlab "github.com/xanzy/go-gitlab"
+)
+
+// Gitlab removes an applied quick action from the note and adds a system note for it, a /spend line left in a note was never applied
+var (
+	spentSystemNote    = regexp.MustCompile(`^(added|subtracted) (.+?) of time spent(?: at (\d{4}-\d{2}-\d{2}))?$`)
+	estimateSystemNote = regexp.MustCompile(`^changed time estimate to (.+)$`)
+	spendQuickAction   = regexp.MustCompile(`^/(?:spend|spent)\s+(-?)(.+?)(?:\s+(\d{4}-\d{2}-\d{2}))?$`)
+	estimateAction     = regexp.MustCompile(`^/estimate\s+(.+)$`)
+	durationPart       = regexp.MustCompile(`(\d+(?:\.\d+)?)\s*(mo|w|d|h|m|s)`)
+)
+
+// Seconds of a Gitlab duration such as 1w 2d 3h 30m, with Gitlab's default 8 hour days, 5 day weeks and 4 week months
+func parseGitlabDuration(s string) (int, bool) {
+	units := map[string]float64{"mo": 4 * 5 * 8 * 3600, "w": 5 * 8 * 3600, "d": 8 * 3600, "h": 3600, "m": 60, "s": 1}
+	rest := strings.TrimSpace(s)
+	var seconds float64
+	for _, part := range durationPart.FindAllStringSubmatch(rest, -1) {
+		value, err := strconv.ParseFloat(part[1], 64)
+		if err != nil {
+			return 0, false
+		}
+		seconds += value * units[part[2]]
+		rest = strings.Replace(rest, part[0], "", 1)
+	}
+	if seconds == 0 || strings.TrimSpace(rest) != "" {
+		return 0, false
+	}
+
+	return int(seconds), true
+}
+
+// Time spent per day and last estimate of an issue according to its notes
+type notesTime struct {
+	spentPerDay map[string]int
+	estimate    int
+}
+
+func (n *notesTime) add(note *gitlab.Note) {
+	for _, line := range strings.Split(note.Body, "\n") {
+		line = strings.TrimSpace(line)
+		day := ""
+		if note.CreatedAt != nil {
+			day = note.CreatedAt.UTC().Format("2006-01-02")
+		}
+
+		if note.System {
+			switch {
+			case line == "removed time spent":
+				n.spentPerDay = make(map[string]int)
+			case line == "removed time estimate":
+				n.estimate = 0
+			case spentSystemNote.MatchString(line):
+				match := spentSystemNote.FindStringSubmatch(line)
+				seconds, ok := parseGitlabDuration(match[2])
+				if !ok {
+					logger.Warn("Could not parse the time spent of a note", "note", line)
+					continue
+				}
+				if match[1] == "subtracted" {
+					seconds = -seconds
+				}
+				if match[3] != "" {
+					day = match[3]
+				}
+				n.spentPerDay[day] += seconds
+			case estimateSystemNote.MatchString(line):
+				if seconds, ok := parseGitlabDuration(estimateSystemNote.FindStringSubmatch(line)[1]); ok {
+					n.estimate = seconds
+				}
+			}
+			continue
+		}
+
+		switch {
+		case spendQuickAction.MatchString(line):
+			match := spendQuickAction.FindStringSubmatch(line)
+			seconds, ok := parseGitlabDuration(match[2])
+			if !ok {
+				continue
+			}
+			if match[1] == "-" {
+				seconds = -seconds
+			}
+			if match[3] != "" {
+				day = match[3]
+			}
+			n.spentPerDay[day] += seconds
+		case estimateAction.MatchString(line):
+			if seconds, ok := parseGitlabDuration(estimateAction.FindStringSubmatch(line)[1]); ok {
+				n.estimate = seconds
+			}
+		}
+	}
+}
+
+func (n *notesTime) total() int {
+	total := 0
+	for _, seconds := range n.spentPerDay {
+		total += seconds
+	}
+
+	return total
+}
+
+// Every note of an issue or merge request, oldest first so later estimates and removals win
+func getNotes(issue Issue, gitlabClient *gitlab.Client) ([]*gitlab.Note, error) {
+	iid, err := strconv.Atoi(issue.IID)
+	if err != nil {
+		return nil, err
+	}
+
+	var notes []*gitlab.Note
+	listOptions := gitlab.ListOptions{PerPage: 100}
+	for {
+		var page []*gitlab.Note
+		var resp *gitlab.Response
+		if issue.MergeRequest {
+			page, resp, err = gitlabClient.Notes.ListMergeRequestNotes(issue.ProjectPath, iid, &gitlab.ListMergeRequestNotesOptions{ListOptions: listOptions, OrderBy: gitlab.String("created_at"), Sort: gitlab.String("asc")})
+		} else {
+			page, resp, err = gitlabClient.Notes.ListIssueNotes(issue.ProjectPath, iid, &gitlab.ListIssueNotesOptions{ListOptions: listOptions, OrderBy: gitlab.String("created_at"), Sort: gitlab.String("asc")})
+		}
+		if err != nil {
+			return nil, err
+		}
+		notes = append(notes, page...)
+
+		if resp.NextPage == 0 {
+			break
+		}
+		listOptions.Page = resp.NextPage
+	}
+
+	return notes, nil
+}
+
+// VERIFY_NOTES audit, the time spent and estimate of the notes of every fetched issue against its timelogs, mismatches are listed and never reconciled
+func verifyNotes(timelogData *TimelogData, gitlabClient *gitlab.Client, excludedUsernames []string, reportOptions ReportOptions) (int, error) {
+	hours := func(seconds int) float32 {
+		return roundHours(float32(seconds)/3600, reportOptions.RoundingMode)
+	}
+
+	log.Println(sectionHeader(translate(reportOptions.Language, "-- Notes vs timelogs --")))
+	mismatches := 0
+	for _, issue := range timelogData.Project.Issues.Nodes {
+		notes, err := getNotes(issue, gitlabClient)
+		if err != nil {
+			return mismatches, fmt.Errorf("could not list the notes of %s: %w", issueRef(issue.ProjectPath, issue.IID, issue.MergeRequest, reportOptions), err)
+		}
+		fromNotes := notesTime{spentPerDay: make(map[string]int)}
+		for _, note := range notes {
+			// the timelogs of EXCLUDE_USERS are already dropped, so are the notes they wrote
+			if slices.Contains(excludedUsernames, note.Author.Username) {
+				continue
+			}
+			fromNotes.add(note)
+		}
+
+		fromTimelogs := make(map[string]int)
+		timelogsTotal := 0
+		for _, timelog := range issue.Timelogs.Nodes {
+			// days are compared in UTC, Gitlab's time for the notes and the spentAt of a /spend without date
+			day := timelog.SpentAt
+			if spentAt, err := time.Parse(time.RFC3339, timelog.SpentAt); err == nil {
+				day = spentAt.UTC().Format("2006-01-02")
+			}
+			fromTimelogs[day] += timelog.TimeSpent
+			timelogsTotal += timelog.TimeSpent
+		}
+
+		// merge requests are not fetched with their estimate
+		estimateMismatch := !issue.MergeRequest && fromNotes.estimate != issue.TimeEstimate
+		if fromNotes.total() == timelogsTotal && !estimateMismatch {
+			continue
+		}
+		mismatches++
+
+		log.Printf("%s: %s%s", issueRef(issue.ProjectPath, issue.IID, issue.MergeRequest, reportOptions), issue.Title, issueLink(issue.ProjectPath, issue.IID, issue.MergeRequest, reportOptions))
+		if fromNotes.total() != timelogsTotal {
+			log.Printf("  spent : %.1fh in notes, %.1fh in timelogs (%+.1fh)", hours(fromNotes.total()), hours(timelogsTotal), hours(fromNotes.total()-timelogsTotal))
+			days := make(map[string]bool)
+			for day := range fromNotes.spentPerDay {
+				days[day] = true
+			}
+			for day := range fromTimelogs {
+				days[day] = true
+			}
+			var sortedDays []string
+			for day := range days {
+				if fromNotes.spentPerDay[day] != fromTimelogs[day] {
+					sortedDays = append(sortedDays, day)
+				}
+			}
+			sort.Strings(sortedDays)
+			for _, day := range sortedDays {
+				log.Printf("    %s : %.1fh in notes, %.1fh in timelogs", day, hours(fromNotes.spentPerDay[day]), hours(fromTimelogs[day]))
+			}
+		}
+		if estimateMismatch {
+			log.Printf("  estimate : %.1fh in notes, %.1fh on the issue", hours(fromNotes.estimate), hours(issue.TimeEstimate))
+		}
+	}
+	log.Printf("%d of %d issues do not match", mismatches, len(timelogData.Project.Issues.Nodes))
+
+	return mismatches, nil
+}
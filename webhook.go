@@ -0,0 +1,60 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Bounds the whole post, retries included, so a slow consumer can not hold a nightly run for long
+const webhookTimeout = 30 * time.Second
+
+// Split WEBHOOK_AUTH_HEADER, a "Name: value" header line such as "Authorization: Bearer xxx"
+func parseWebhookAuthHeader(header string) (string, string, error) {
+	if header == "" {
+		return "", "", nil
+	}
+	name, value, found := strings.Cut(header, ":")
+	name, value = strings.TrimSpace(name), strings.TrimSpace(value)
+	if !found || name == "" || strings.ContainsAny(name, " \t") {
+		return "", "", fmt.Errorf("WEBHOOK_AUTH_HEADER must be a header line such as \"Authorization: Bearer xxx\"")
+	}
+
+	return name, value, nil
+}
+
+// POST the JSON report to a webhook, transient failures are retried and errors only logged so the report is never lost
+func postWebhook(webhookURL string, authName string, authValue string, report SpentTimeReport, maxRetries int, transport http.RoundTripper) {
+	var payload bytes.Buffer
+	if err := writeSpentTimeReport(&payload, report); err != nil {
+		logger.Error("Failed to post the report to the webhook", "error", err)
+		return
+	}
+
+	// a bytes.Reader body sets GetBody, so every retry sends the whole report again
+	req, err := http.NewRequest(http.MethodPost, webhookURL, bytes.NewReader(payload.Bytes()))
+	if err != nil {
+		logger.Error("Failed to post the report to the webhook", "error", redact(err.Error(), []string{webhookURL}))
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if authName != "" {
+		req.Header.Set(authName, authValue)
+	}
+
+	// the webhook URL may hold a secret, it is never logged
+	client := &http.Client{Timeout: webhookTimeout, Transport: &retryTransport{retries: maxRetries, baseDelay: time.Second, next: transport}}
+	resp, err := client.Do(req)
+	if err != nil {
+		logger.Error("Failed to post the report to the webhook", "error", redact(err.Error(), []string{webhookURL, authValue}))
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		logger.Error("Failed to post the report to the webhook", "status", resp.Status)
+		return
+	}
+	logger.Info("Posted the report to the webhook", "status", resp.Status)
+}